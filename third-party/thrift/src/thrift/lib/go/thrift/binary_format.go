@@ -0,0 +1,419 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift/types"
+)
+
+// binaryFormat is the original Thrift TBinaryProtocol: every primitive is
+// written at its natural width, strings/binary are length-prefixed, and
+// containers are a 1-byte element type followed by a 4-byte size.
+type binaryFormat struct {
+	trans    io.ReadWriter
+	skipOpts types.SkipOptions
+}
+
+func newBinaryFormat(trans io.ReadWriter) types.Format {
+	return &binaryFormat{trans: trans, skipOpts: types.DefaultSkipOptions}
+}
+
+// SetSkipOptions overrides the bounds Skip enforces; see types.SkipOptions.
+func (p *binaryFormat) SetSkipOptions(opts types.SkipOptions) {
+	p.skipOpts = opts
+}
+
+func (p *binaryFormat) Flush() error { return flush(p.trans) }
+
+func (p *binaryFormat) WriteMessageBegin(name string, typeID byte, seqID int32) error {
+	if err := p.WriteString(name); err != nil {
+		return err
+	}
+	if err := p.WriteByte(typeID); err != nil {
+		return err
+	}
+	return p.WriteI32(seqID)
+}
+func (p *binaryFormat) WriteMessageEnd() error { return nil }
+
+func (p *binaryFormat) WriteStructBegin(name string) error { return nil }
+func (p *binaryFormat) WriteStructEnd() error              { return nil }
+
+func (p *binaryFormat) WriteFieldBegin(name string, typeID types.Type, id int16) error {
+	if err := p.WriteByte(byte(typeID)); err != nil {
+		return err
+	}
+	return p.WriteI16(id)
+}
+func (p *binaryFormat) WriteFieldEnd() error { return nil }
+func (p *binaryFormat) WriteFieldStop() error {
+	return p.WriteByte(byte(types.STOP))
+}
+
+func (p *binaryFormat) WriteMapBegin(keyType, valueType types.Type, size int) error {
+	if err := p.WriteByte(byte(keyType)); err != nil {
+		return err
+	}
+	if err := p.WriteByte(byte(valueType)); err != nil {
+		return err
+	}
+	return p.WriteI32(int32(size))
+}
+func (p *binaryFormat) WriteMapEnd() error { return nil }
+
+func (p *binaryFormat) WriteListBegin(elemType types.Type, size int) error {
+	if err := p.WriteByte(byte(elemType)); err != nil {
+		return err
+	}
+	return p.WriteI32(int32(size))
+}
+func (p *binaryFormat) WriteListEnd() error { return nil }
+
+func (p *binaryFormat) WriteSetBegin(elemType types.Type, size int) error {
+	return p.WriteListBegin(elemType, size)
+}
+func (p *binaryFormat) WriteSetEnd() error { return nil }
+
+func (p *binaryFormat) WriteBool(value bool) error {
+	if value {
+		return p.WriteByte(1)
+	}
+	return p.WriteByte(0)
+}
+
+func (p *binaryFormat) WriteByte(value byte) error {
+	_, err := p.trans.Write([]byte{value})
+	return err
+}
+
+func (p *binaryFormat) WriteI16(value int16) error {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], uint16(value))
+	_, err := p.trans.Write(buf[:])
+	return err
+}
+
+func (p *binaryFormat) WriteI32(value int32) error {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(value))
+	_, err := p.trans.Write(buf[:])
+	return err
+}
+
+func (p *binaryFormat) WriteI64(value int64) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(value))
+	_, err := p.trans.Write(buf[:])
+	return err
+}
+
+func (p *binaryFormat) WriteDouble(value float64) error {
+	return p.WriteI64(int64(math.Float64bits(value)))
+}
+
+func (p *binaryFormat) WriteFloat(value float32) error {
+	return p.WriteI32(int32(math.Float32bits(value)))
+}
+
+func (p *binaryFormat) WriteString(value string) error {
+	return p.WriteBinary([]byte(value))
+}
+
+func (p *binaryFormat) WriteBinary(value []byte) error {
+	if err := p.WriteI32(int32(len(value))); err != nil {
+		return err
+	}
+	_, err := p.trans.Write(value)
+	return err
+}
+
+// WriteUUID writes value as its raw 16 bytes, matching the binary protocol
+// convention of fixed-width encodings for fixed-size types.
+func (p *binaryFormat) WriteUUID(value types.UUID) error {
+	_, err := p.trans.Write(value[:])
+	return err
+}
+
+func (p *binaryFormat) ReadMessageBegin() (name string, typeID byte, seqID int32, err error) {
+	if name, err = p.ReadString(); err != nil {
+		return
+	}
+	if typeID, err = p.ReadByte(); err != nil {
+		return
+	}
+	seqID, err = p.ReadI32()
+	return
+}
+func (p *binaryFormat) ReadMessageEnd() error { return nil }
+
+func (p *binaryFormat) ReadStructBegin() (string, error) { return "", nil }
+func (p *binaryFormat) ReadStructEnd() error             { return nil }
+
+func (p *binaryFormat) ReadFieldBegin() (name string, typeID types.Type, id int16, err error) {
+	var t byte
+	if t, err = p.ReadByte(); err != nil {
+		return
+	}
+	typeID = types.Type(t)
+	if typeID == types.STOP {
+		return
+	}
+	id, err = p.ReadI16()
+	return
+}
+func (p *binaryFormat) ReadFieldEnd() error { return nil }
+
+func (p *binaryFormat) ReadMapBegin() (keyType, valueType types.Type, size int, err error) {
+	var kt, vt byte
+	if kt, err = p.ReadByte(); err != nil {
+		return
+	}
+	if vt, err = p.ReadByte(); err != nil {
+		return
+	}
+	var sz int32
+	if sz, err = p.ReadI32(); err != nil {
+		return
+	}
+	return types.Type(kt), types.Type(vt), int(sz), nil
+}
+func (p *binaryFormat) ReadMapEnd() error { return nil }
+
+func (p *binaryFormat) ReadListBegin() (elemType types.Type, size int, err error) {
+	var et byte
+	if et, err = p.ReadByte(); err != nil {
+		return
+	}
+	var sz int32
+	if sz, err = p.ReadI32(); err != nil {
+		return
+	}
+	return types.Type(et), int(sz), nil
+}
+func (p *binaryFormat) ReadListEnd() error { return nil }
+
+func (p *binaryFormat) ReadSetBegin() (types.Type, int, error) { return p.ReadListBegin() }
+func (p *binaryFormat) ReadSetEnd() error                      { return nil }
+
+func (p *binaryFormat) ReadBool() (bool, error) {
+	b, err := p.ReadByte()
+	return b != 0, err
+}
+
+func (p *binaryFormat) ReadByte() (byte, error) {
+	var buf [1]byte
+	_, err := io.ReadFull(p.trans, buf[:])
+	return buf[0], err
+}
+
+func (p *binaryFormat) ReadI16() (int16, error) {
+	var buf [2]byte
+	if _, err := io.ReadFull(p.trans, buf[:]); err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(buf[:])), nil
+}
+
+func (p *binaryFormat) ReadI32() (int32, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(p.trans, buf[:]); err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(buf[:])), nil
+}
+
+func (p *binaryFormat) ReadI64() (int64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(p.trans, buf[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(buf[:])), nil
+}
+
+func (p *binaryFormat) ReadDouble() (float64, error) {
+	v, err := p.ReadI64()
+	return math.Float64frombits(uint64(v)), err
+}
+
+func (p *binaryFormat) ReadFloat() (float32, error) {
+	v, err := p.ReadI32()
+	return math.Float32frombits(uint32(v)), err
+}
+
+func (p *binaryFormat) ReadString() (string, error) {
+	b, err := p.ReadBinary()
+	return string(b), err
+}
+
+// ReadUUID reads a raw 16-byte UUID off the wire.
+func (p *binaryFormat) ReadUUID() (types.UUID, error) {
+	var u types.UUID
+	_, err := io.ReadFull(p.trans, u[:])
+	return u, err
+}
+
+func (p *binaryFormat) ReadBinary() ([]byte, error) {
+	size, err := p.ReadI32()
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, types.NewProtocolException(types.NEGATIVE_SIZE, "binaryFormat: negative binary size")
+	}
+	if int64(size) > p.skipOpts.MaxBytes || int(size) > p.skipOpts.MaxContainerSize {
+		return nil, types.NewProtocolException(types.SIZE_LIMIT, "binaryFormat: declared binary size exceeds configured limits")
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(p.trans, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Skip reads and discards a single value of typeID off the wire, recursing
+// into containers and structs as needed. It enforces p.skipOpts so a
+// malformed or adversarial stream -- e.g. list<list<...>> nested past
+// MaxDepth, or a container declaring a multi-billion-element size -- fails
+// fast with a ProtocolException instead of recursing or iterating without
+// bound.
+func (p *binaryFormat) Skip(typeID types.Type) error {
+	budget := p.skipOpts.MaxBytes
+	return p.skip(typeID, 0, &budget)
+}
+
+func (p *binaryFormat) skip(typeID types.Type, depth int, byteBudget *int64) error {
+	if depth > p.skipOpts.MaxDepth {
+		return types.NewProtocolException(types.DEPTH_LIMIT_EXCEEDED, "binaryFormat: Skip exceeded MaxDepth")
+	}
+	switch typeID {
+	case types.BOOL:
+		_, err := p.ReadBool()
+		return p.chargeSkip(byteBudget, 1, err)
+	case types.BYTE:
+		_, err := p.ReadByte()
+		return p.chargeSkip(byteBudget, 1, err)
+	case types.I16:
+		_, err := p.ReadI16()
+		return p.chargeSkip(byteBudget, 2, err)
+	case types.I32:
+		_, err := p.ReadI32()
+		return p.chargeSkip(byteBudget, 4, err)
+	case types.I64:
+		_, err := p.ReadI64()
+		return p.chargeSkip(byteBudget, 8, err)
+	case types.DOUBLE:
+		_, err := p.ReadDouble()
+		return p.chargeSkip(byteBudget, 8, err)
+	case types.FLOAT:
+		_, err := p.ReadFloat()
+		return p.chargeSkip(byteBudget, 4, err)
+	case types.STRING:
+		v, err := p.ReadBinary()
+		return p.chargeSkip(byteBudget, int64(len(v)), err)
+	case types.TYPE_UUID:
+		_, err := p.ReadUUID()
+		return p.chargeSkip(byteBudget, 16, err)
+	case types.STRUCT:
+		if _, err := p.ReadStructBegin(); err != nil {
+			return err
+		}
+		for {
+			_, ft, _, err := p.ReadFieldBegin()
+			if err != nil {
+				return err
+			}
+			if ft == types.STOP {
+				break
+			}
+			if err := p.skip(ft, depth+1, byteBudget); err != nil {
+				return err
+			}
+			if err := p.ReadFieldEnd(); err != nil {
+				return err
+			}
+		}
+		return p.ReadStructEnd()
+	case types.MAP:
+		kt, vt, size, err := p.ReadMapBegin()
+		if err != nil {
+			return err
+		}
+		if err := p.checkContainerSize(size); err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			if err := p.skip(kt, depth+1, byteBudget); err != nil {
+				return err
+			}
+			if err := p.skip(vt, depth+1, byteBudget); err != nil {
+				return err
+			}
+		}
+		return p.ReadMapEnd()
+	case types.SET, types.LIST:
+		var et types.Type
+		var size int
+		var err error
+		if typeID == types.SET {
+			et, size, err = p.ReadSetBegin()
+		} else {
+			et, size, err = p.ReadListBegin()
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.checkContainerSize(size); err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			if err := p.skip(et, depth+1, byteBudget); err != nil {
+				return err
+			}
+		}
+		if typeID == types.SET {
+			return p.ReadSetEnd()
+		}
+		return p.ReadListEnd()
+	default:
+		return types.NewProtocolException(types.INVALID_DATA, "binaryFormat: cannot skip unknown type")
+	}
+}
+
+func (p *binaryFormat) checkContainerSize(size int) error {
+	if size < 0 {
+		return types.NewProtocolException(types.NEGATIVE_SIZE, "binaryFormat: negative container size")
+	}
+	if size > p.skipOpts.MaxContainerSize {
+		return types.NewProtocolException(types.SIZE_LIMIT, "binaryFormat: Skip container size exceeds MaxContainerSize")
+	}
+	return nil
+}
+
+func (p *binaryFormat) chargeSkip(byteBudget *int64, n int64, err error) error {
+	if err != nil {
+		return err
+	}
+	*byteBudget -= n
+	if *byteBudget < 0 {
+		return types.NewProtocolException(types.SIZE_LIMIT, "binaryFormat: Skip exceeded MaxBytes")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"sync"
+)
+
+// bufferPool is a sync.Pool of *bytes.Buffer. THeaderTransport draws its
+// write buffer from one of these per THRIFT-5490 instead of allocating its
+// own, taking it when the transport is created and returning it on Close;
+// httpClient and framedTransport go further, taking a buffer on the first
+// Write and returning it at the end of Flush (see headerWriteBufferPool,
+// httpRequestBufferPool, framedWriteBufferPool).
+type bufferPool struct {
+	pool sync.Pool
+}
+
+func newBufferPool() *bufferPool {
+	return &bufferPool{
+		pool: sync.Pool{
+			New: func() interface{} { return &bytes.Buffer{} },
+		},
+	}
+}
+
+// Get returns an empty buffer, either reused from the pool or freshly
+// allocated.
+func (p *bufferPool) Get() *bytes.Buffer {
+	return p.pool.Get().(*bytes.Buffer)
+}
+
+// Put resets buf and returns it to the pool. Callers must not retain buf
+// after calling Put.
+func (p *bufferPool) Put(buf *bytes.Buffer) {
+	buf.Reset()
+	p.pool.Put(buf)
+}
+
+var (
+	headerWriteBufferPool = newBufferPool()
+	httpRequestBufferPool = newBufferPool()
+	framedWriteBufferPool = newBufferPool()
+)
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"compress/flate"
+	"io"
+)
+
+// CompressionLevel selects the DEFLATE strategy used by a
+// compressedTransport. The zero value is DefaultCompression.
+type CompressionLevel int
+
+const (
+	// DefaultCompression asks compress/flate for its usual speed/ratio
+	// tradeoff.
+	DefaultCompression CompressionLevel = iota
+	// BestSpeed favors throughput over ratio.
+	BestSpeed
+	// BestCompression favors ratio over throughput.
+	BestCompression
+	// HuffmanOnly skips LZ77 match search entirely and emits only Huffman
+	// entropy coding. The output is still a valid RFC 1951 stream, so any
+	// standard inflater decodes it same as any other level -- this is
+	// purely a write-side CPU/ratio tradeoff for payloads (images,
+	// already-Snappy'd fields) where LZ77 search wastes cycles finding
+	// matches that aren't there, but skewed byte frequencies still leave
+	// something for Huffman coding to take advantage of.
+	HuffmanOnly
+)
+
+func (l CompressionLevel) flateLevel() int {
+	switch l {
+	case BestSpeed:
+		return flate.BestSpeed
+	case BestCompression:
+		return flate.BestCompression
+	case HuffmanOnly:
+		return flate.HuffmanOnly
+	default:
+		return flate.DefaultCompression
+	}
+}
+
+// CompressOpts configures NewCompressedTransport.
+type CompressOpts struct {
+	Level CompressionLevel
+}
+
+// compressedTransport wraps another Transport, compressing everything
+// written to it and decompressing everything read from it with
+// compress/flate. Every Flush emits a Z_SYNC_FLUSH, so the peer can inflate
+// each Thrift message as soon as it arrives instead of waiting for the end
+// of the stream. All messages share one DEFLATE stream: a message is not
+// decodable on its own, only after every message written before it.
+type compressedTransport struct {
+	inner  io.ReadWriteCloser
+	writer *flate.Writer
+	reader io.ReadCloser
+}
+
+// NewCompressedTransport wraps inner so that writes are DEFLATE-compressed
+// and reads are transparently decompressed. inner can be any Transport --
+// a MemoryBuffer, an httpClient, a TCP stream -- including ones driven
+// from separate reader/writer goroutines, since the write and read paths
+// here touch disjoint state.
+func NewCompressedTransport(inner io.ReadWriteCloser, opts CompressOpts) io.ReadWriteCloser {
+	// flate.NewWriter only errors for an out-of-range level, and
+	// flateLevel() never produces one.
+	w, _ := flate.NewWriter(inner, opts.Level.flateLevel())
+	return &compressedTransport{
+		inner:  inner,
+		writer: w,
+		reader: flate.NewReader(inner),
+	}
+}
+
+func (t *compressedTransport) Write(b []byte) (int, error) {
+	n, err := t.writer.Write(b)
+	if err != nil {
+		err = NewTransportExceptionFromError(err)
+	}
+	return n, err
+}
+
+// Flush emits a Z_SYNC_FLUSH so the peer can inflate the message that was
+// just written without waiting for more data, then flushes inner.
+func (t *compressedTransport) Flush() error {
+	if err := t.writer.Flush(); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	return flush(t.inner)
+}
+
+func (t *compressedTransport) Read(b []byte) (int, error) {
+	n, err := t.reader.Read(b)
+	if err != nil && err != io.EOF {
+		err = NewTransportExceptionFromError(err)
+	}
+	return n, err
+}
+
+func (t *compressedTransport) Close() error {
+	t.writer.Close()
+	t.reader.Close()
+	return t.inner.Close()
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import "math"
+
+// SpecialFloatValue names one of the IEEE-754 special values so tests and
+// protocol implementations don't need to spell out math.Inf(1) etc.
+// everywhere they need a canonical NaN/Inf to round-trip.
+type SpecialFloatValue int
+
+const (
+	INFINITY SpecialFloatValue = iota
+	NEGATIVE_INFINITY
+	NAN
+)
+
+// Float64 returns v as a float64.
+func (v SpecialFloatValue) Float64() float64 {
+	switch v {
+	case INFINITY:
+		return math.Inf(1)
+	case NEGATIVE_INFINITY:
+		return math.Inf(-1)
+	default:
+		return math.NaN()
+	}
+}
+
+// Float32 returns v as a float32.
+func (v SpecialFloatValue) Float32() float32 {
+	return float32(v.Float64())
+}
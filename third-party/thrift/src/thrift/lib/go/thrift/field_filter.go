@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"fmt"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift/types"
+)
+
+// FieldFilterOp is one opcode in a FieldFilter program.
+type FieldFilterOp byte
+
+const (
+	// OpLoadFieldID loads the id of the field currently being classified
+	// into the accumulator.
+	OpLoadFieldID FieldFilterOp = iota
+	// OpLoadType loads the wire type of the field currently being
+	// classified into the accumulator.
+	OpLoadType
+	// OpJEQ jumps to Insn.JT if the accumulator equals Insn.Imm, otherwise
+	// falls through to the next instruction.
+	OpJEQ
+	// OpJGT jumps to Insn.JT if the accumulator is greater than Insn.Imm,
+	// otherwise falls through to the next instruction.
+	OpJGT
+	// OpAccept ends classification of the current field: materialize it.
+	OpAccept
+	// OpSkip ends classification of the current field: discard it via
+	// Format.Skip without materializing a value.
+	OpSkip
+	// OpRet ends classification of the *struct*: the current field and
+	// every field after it are skipped without running the program again.
+	OpRet
+)
+
+// FieldFilterInsn is one instruction in a FieldFilter program. Imm is the
+// comparison immediate for OpJEQ/OpJGT; JT is the jump target (an absolute
+// index into the program) for OpJEQ/OpJGT.
+type FieldFilterInsn struct {
+	Op  FieldFilterOp
+	Imm int64
+	JT  int
+}
+
+// fieldFilterDecision is the outcome of running a FieldFilter program
+// against one field header.
+type fieldFilterDecision byte
+
+const (
+	decideAccept fieldFilterDecision = iota
+	decideSkip
+	decideStop
+)
+
+// FieldFilter is a compiled, verified program that classifies struct
+// fields as they stream off the wire, without materializing the ones it
+// rejects. Build one with CompileFieldFilter.
+//
+// The intended use is a proxy that only cares about a handful of fields
+// out of a large struct: compile the program once, then run it against
+// every field header between ReadStructBegin and the terminating STOP,
+// calling Format.Skip on anything it doesn't accept.
+type FieldFilter struct {
+	prog []FieldFilterInsn
+}
+
+// CompileFieldFilter verifies prog and returns a FieldFilter that can run
+// it. Verification rejects anything that would keep execution from being
+// O(n) in field count: out-of-range or backward jump targets, and
+// instructions unreachable from the start of the program.
+func CompileFieldFilter(prog []FieldFilterInsn) (*FieldFilter, error) {
+	if len(prog) == 0 {
+		return nil, fmt.Errorf("FieldFilter: empty program")
+	}
+
+	reachable := make([]bool, len(prog))
+	reachable[0] = true
+	for i, insn := range prog {
+		switch insn.Op {
+		case OpLoadFieldID, OpLoadType:
+			if i+1 >= len(prog) {
+				return nil, fmt.Errorf("FieldFilter: instruction %d falls off the end of the program", i)
+			}
+			if reachable[i] {
+				reachable[i+1] = true
+			}
+		case OpJEQ, OpJGT:
+			if insn.JT <= i || insn.JT >= len(prog) {
+				return nil, fmt.Errorf("FieldFilter: instruction %d has an out-of-range or backward jump target %d", i, insn.JT)
+			}
+			if i+1 >= len(prog) {
+				return nil, fmt.Errorf("FieldFilter: instruction %d falls off the end of the program", i)
+			}
+			if reachable[i] {
+				reachable[i+1] = true
+				reachable[insn.JT] = true
+			}
+		case OpAccept, OpSkip, OpRet:
+			// Terminal: no fallthrough to verify.
+		default:
+			return nil, fmt.Errorf("FieldFilter: instruction %d has unknown opcode %d", i, insn.Op)
+		}
+	}
+	for i, ok := range reachable {
+		if !ok {
+			return nil, fmt.Errorf("FieldFilter: instruction %d is unreachable", i)
+		}
+	}
+
+	return &FieldFilter{prog: prog}, nil
+}
+
+// run executes the program against one field header and returns what the
+// caller should do with it.
+func (f *FieldFilter) run(fieldID int16, wireType types.Type) fieldFilterDecision {
+	var acc int64
+	pc := 0
+	for {
+		insn := f.prog[pc]
+		switch insn.Op {
+		case OpLoadFieldID:
+			acc = int64(fieldID)
+			pc++
+		case OpLoadType:
+			acc = int64(wireType)
+			pc++
+		case OpJEQ:
+			if acc == insn.Imm {
+				pc = insn.JT
+			} else {
+				pc++
+			}
+		case OpJGT:
+			if acc > insn.Imm {
+				pc = insn.JT
+			} else {
+				pc++
+			}
+		case OpAccept:
+			return decideAccept
+		case OpSkip:
+			return decideSkip
+		case OpRet:
+			return decideStop
+		}
+	}
+}
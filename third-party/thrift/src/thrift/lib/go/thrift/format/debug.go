@@ -0,0 +1,471 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift/types"
+)
+
+// DebugEvent is one Read*/Write* call intercepted by a DebugFormat.
+type DebugEvent struct {
+	Op    string
+	Type  types.Type
+	ID    int16
+	Size  int
+	Value interface{}
+	Err   error
+}
+
+// String renders e as one line: Op followed by whichever of type, id,
+// size, value and err are set. It carries the same fields NewDebug logs,
+// without the logger's prefix, and names the field id "id" where the log
+// line uses "field_id".
+func (e DebugEvent) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.Op)
+	if e.Type != types.STOP {
+		fmt.Fprintf(&sb, " type=%v", e.Type)
+	}
+	if e.ID != 0 {
+		fmt.Fprintf(&sb, " id=%d", e.ID)
+	}
+	if e.Size != 0 {
+		fmt.Fprintf(&sb, " size=%d", e.Size)
+	}
+	if e.Value != nil {
+		fmt.Fprintf(&sb, " value=%v", e.Value)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&sb, " err=%v", e.Err)
+	}
+	return sb.String()
+}
+
+const defaultDebugRingCapacity = 1024
+
+// debugRing is a fixed-capacity, overwrite-oldest ring buffer of
+// DebugEvents, so a long-running or fuzzed session doesn't grow Dump()
+// without bound.
+type debugRing struct {
+	events []DebugEvent
+	next   int
+	full   bool
+}
+
+func newDebugRing(capacity int) *debugRing {
+	return &debugRing{events: make([]DebugEvent, capacity)}
+}
+
+func (r *debugRing) add(e DebugEvent) {
+	r.events[r.next] = e
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *debugRing) dump() []DebugEvent {
+	if !r.full {
+		out := make([]DebugEvent, r.next)
+		copy(out, r.events[:r.next])
+		return out
+	}
+	out := make([]DebugEvent, len(r.events))
+	copy(out, r.events[r.next:])
+	copy(out[len(r.events)-r.next:], r.events[:r.next])
+	return out
+}
+
+// DebugFormat wraps another types.Format, delegating every call to it
+// while logging a structured line per call -- operation name, field
+// id/type, container size, and the value or error produced. Build one
+// with NewDebug (logging only) or NewDebugWithBuffer (logging plus an
+// in-memory event buffer retrievable with Dump).
+type DebugFormat struct {
+	inner  types.Format
+	logger *slog.Logger
+	prefix string
+	ring   *debugRing
+}
+
+// NewDebug wraps inner, logging every call to logger with prefix prepended
+// to the log message.
+func NewDebug(inner types.Format, logger *slog.Logger, prefix string) types.Format {
+	return &DebugFormat{inner: inner, logger: logger, prefix: prefix}
+}
+
+// NewDebugWithBuffer is NewDebug plus an in-memory ring buffer of every
+// call, retrievable with Dump -- meant for tests that want to print the
+// exact call sequence leading up to a failure. logger may be nil to
+// capture without also logging.
+func NewDebugWithBuffer(inner types.Format, logger *slog.Logger, prefix string) *DebugFormat {
+	return &DebugFormat{inner: inner, logger: logger, prefix: prefix, ring: newDebugRing(defaultDebugRingCapacity)}
+}
+
+// Dump returns every call captured so far, oldest first. It returns nil if
+// d wasn't built with NewDebugWithBuffer.
+func (d *DebugFormat) Dump() []DebugEvent {
+	if d.ring == nil {
+		return nil
+	}
+	return d.ring.dump()
+}
+
+// DiffDebugStreams renders a line-by-line diff between two DebugEvent
+// streams -- e.g. a passing run's Dump() against a failing one's -- to
+// make the point of divergence obvious at a glance.
+func DiffDebugStreams(a, b []DebugEvent) string {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		la, lb := "<missing>", "<missing>"
+		if i < len(a) {
+			la = a[i].String()
+		}
+		if i < len(b) {
+			lb = b[i].String()
+		}
+		if la == lb {
+			fmt.Fprintf(&sb, "  %s\n", la)
+		} else {
+			fmt.Fprintf(&sb, "- %s\n+ %s\n", la, lb)
+		}
+	}
+	return sb.String()
+}
+
+func (d *DebugFormat) record(e DebugEvent) {
+	if d.ring != nil {
+		d.ring.add(e)
+	}
+	if d.logger == nil {
+		return
+	}
+	msg := d.prefix + e.Op
+	var attrs []any
+	if e.Type != types.STOP {
+		attrs = append(attrs, slog.String("type", e.Type.String()))
+	}
+	if e.ID != 0 {
+		attrs = append(attrs, slog.Int("field_id", int(e.ID)))
+	}
+	if e.Size != 0 {
+		attrs = append(attrs, slog.Int("size", e.Size))
+	}
+	if e.Value != nil {
+		attrs = append(attrs, slog.Any("value", e.Value))
+	}
+	if e.Err != nil {
+		d.logger.Error(msg, append(attrs, slog.Any("err", e.Err))...)
+		return
+	}
+	d.logger.Debug(msg, attrs...)
+}
+
+func (d *DebugFormat) Flush() error {
+	err := d.inner.Flush()
+	d.record(DebugEvent{Op: "Flush", Err: err})
+	return err
+}
+
+func (d *DebugFormat) SetSkipOptions(opts types.SkipOptions) {
+	d.inner.SetSkipOptions(opts)
+	d.record(DebugEvent{Op: "SetSkipOptions", Value: opts})
+}
+
+func (d *DebugFormat) WriteMessageBegin(name string, typeID byte, seqID int32) error {
+	err := d.inner.WriteMessageBegin(name, typeID, seqID)
+	d.record(DebugEvent{Op: "WriteMessageBegin", Value: fmt.Sprintf("name=%q msgType=%d seqID=%d", name, typeID, seqID), Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteMessageEnd() error {
+	err := d.inner.WriteMessageEnd()
+	d.record(DebugEvent{Op: "WriteMessageEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteStructBegin(name string) error {
+	err := d.inner.WriteStructBegin(name)
+	d.record(DebugEvent{Op: "WriteStructBegin", Value: name, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteStructEnd() error {
+	err := d.inner.WriteStructEnd()
+	d.record(DebugEvent{Op: "WriteStructEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteFieldBegin(name string, typeID types.Type, id int16) error {
+	err := d.inner.WriteFieldBegin(name, typeID, id)
+	d.record(DebugEvent{Op: "WriteFieldBegin", Type: typeID, ID: id, Value: name, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteFieldEnd() error {
+	err := d.inner.WriteFieldEnd()
+	d.record(DebugEvent{Op: "WriteFieldEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteFieldStop() error {
+	err := d.inner.WriteFieldStop()
+	d.record(DebugEvent{Op: "WriteFieldStop", Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteMapBegin(keyType, valueType types.Type, size int) error {
+	err := d.inner.WriteMapBegin(keyType, valueType, size)
+	d.record(DebugEvent{Op: "WriteMapBegin", Type: valueType, Size: size, Value: fmt.Sprintf("keyType=%v", keyType), Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteMapEnd() error {
+	err := d.inner.WriteMapEnd()
+	d.record(DebugEvent{Op: "WriteMapEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteListBegin(elemType types.Type, size int) error {
+	err := d.inner.WriteListBegin(elemType, size)
+	d.record(DebugEvent{Op: "WriteListBegin", Type: elemType, Size: size, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteListEnd() error {
+	err := d.inner.WriteListEnd()
+	d.record(DebugEvent{Op: "WriteListEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteSetBegin(elemType types.Type, size int) error {
+	err := d.inner.WriteSetBegin(elemType, size)
+	d.record(DebugEvent{Op: "WriteSetBegin", Type: elemType, Size: size, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteSetEnd() error {
+	err := d.inner.WriteSetEnd()
+	d.record(DebugEvent{Op: "WriteSetEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteBool(value bool) error {
+	err := d.inner.WriteBool(value)
+	d.record(DebugEvent{Op: "WriteBool", Value: value, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteByte(value byte) error {
+	err := d.inner.WriteByte(value)
+	d.record(DebugEvent{Op: "WriteByte", Value: value, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteI16(value int16) error {
+	err := d.inner.WriteI16(value)
+	d.record(DebugEvent{Op: "WriteI16", Value: value, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteI32(value int32) error {
+	err := d.inner.WriteI32(value)
+	d.record(DebugEvent{Op: "WriteI32", Value: value, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteI64(value int64) error {
+	err := d.inner.WriteI64(value)
+	d.record(DebugEvent{Op: "WriteI64", Value: value, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteDouble(value float64) error {
+	err := d.inner.WriteDouble(value)
+	d.record(DebugEvent{Op: "WriteDouble", Value: value, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteFloat(value float32) error {
+	err := d.inner.WriteFloat(value)
+	d.record(DebugEvent{Op: "WriteFloat", Value: value, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteString(value string) error {
+	err := d.inner.WriteString(value)
+	d.record(DebugEvent{Op: "WriteString", Value: value, Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteBinary(value []byte) error {
+	err := d.inner.WriteBinary(value)
+	d.record(DebugEvent{Op: "WriteBinary", Size: len(value), Err: err})
+	return err
+}
+
+func (d *DebugFormat) WriteUUID(value types.UUID) error {
+	err := d.inner.WriteUUID(value)
+	d.record(DebugEvent{Op: "WriteUUID", Value: value, Err: err})
+	return err
+}
+
+func (d *DebugFormat) ReadMessageBegin() (string, byte, int32, error) {
+	name, typeID, seqID, err := d.inner.ReadMessageBegin()
+	d.record(DebugEvent{Op: "ReadMessageBegin", Value: fmt.Sprintf("name=%q msgType=%d seqID=%d", name, typeID, seqID), Err: err})
+	return name, typeID, seqID, err
+}
+
+func (d *DebugFormat) ReadMessageEnd() error {
+	err := d.inner.ReadMessageEnd()
+	d.record(DebugEvent{Op: "ReadMessageEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) ReadStructBegin() (string, error) {
+	name, err := d.inner.ReadStructBegin()
+	d.record(DebugEvent{Op: "ReadStructBegin", Value: name, Err: err})
+	return name, err
+}
+
+func (d *DebugFormat) ReadStructEnd() error {
+	err := d.inner.ReadStructEnd()
+	d.record(DebugEvent{Op: "ReadStructEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) ReadFieldBegin() (string, types.Type, int16, error) {
+	name, typeID, id, err := d.inner.ReadFieldBegin()
+	d.record(DebugEvent{Op: "ReadFieldBegin", Type: typeID, ID: id, Value: name, Err: err})
+	return name, typeID, id, err
+}
+
+func (d *DebugFormat) ReadFieldEnd() error {
+	err := d.inner.ReadFieldEnd()
+	d.record(DebugEvent{Op: "ReadFieldEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) ReadMapBegin() (types.Type, types.Type, int, error) {
+	keyType, valueType, size, err := d.inner.ReadMapBegin()
+	d.record(DebugEvent{Op: "ReadMapBegin", Type: valueType, Size: size, Value: fmt.Sprintf("keyType=%v", keyType), Err: err})
+	return keyType, valueType, size, err
+}
+
+func (d *DebugFormat) ReadMapEnd() error {
+	err := d.inner.ReadMapEnd()
+	d.record(DebugEvent{Op: "ReadMapEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) ReadListBegin() (types.Type, int, error) {
+	elemType, size, err := d.inner.ReadListBegin()
+	d.record(DebugEvent{Op: "ReadListBegin", Type: elemType, Size: size, Err: err})
+	return elemType, size, err
+}
+
+func (d *DebugFormat) ReadListEnd() error {
+	err := d.inner.ReadListEnd()
+	d.record(DebugEvent{Op: "ReadListEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) ReadSetBegin() (types.Type, int, error) {
+	elemType, size, err := d.inner.ReadSetBegin()
+	d.record(DebugEvent{Op: "ReadSetBegin", Type: elemType, Size: size, Err: err})
+	return elemType, size, err
+}
+
+func (d *DebugFormat) ReadSetEnd() error {
+	err := d.inner.ReadSetEnd()
+	d.record(DebugEvent{Op: "ReadSetEnd", Err: err})
+	return err
+}
+
+func (d *DebugFormat) ReadBool() (bool, error) {
+	value, err := d.inner.ReadBool()
+	d.record(DebugEvent{Op: "ReadBool", Value: value, Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) ReadByte() (byte, error) {
+	value, err := d.inner.ReadByte()
+	d.record(DebugEvent{Op: "ReadByte", Value: value, Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) ReadI16() (int16, error) {
+	value, err := d.inner.ReadI16()
+	d.record(DebugEvent{Op: "ReadI16", Value: value, Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) ReadI32() (int32, error) {
+	value, err := d.inner.ReadI32()
+	d.record(DebugEvent{Op: "ReadI32", Value: value, Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) ReadI64() (int64, error) {
+	value, err := d.inner.ReadI64()
+	d.record(DebugEvent{Op: "ReadI64", Value: value, Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) ReadDouble() (float64, error) {
+	value, err := d.inner.ReadDouble()
+	d.record(DebugEvent{Op: "ReadDouble", Value: value, Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) ReadFloat() (float32, error) {
+	value, err := d.inner.ReadFloat()
+	d.record(DebugEvent{Op: "ReadFloat", Value: value, Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) ReadString() (string, error) {
+	value, err := d.inner.ReadString()
+	d.record(DebugEvent{Op: "ReadString", Value: value, Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) ReadBinary() ([]byte, error) {
+	value, err := d.inner.ReadBinary()
+	d.record(DebugEvent{Op: "ReadBinary", Size: len(value), Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) ReadUUID() (types.UUID, error) {
+	value, err := d.inner.ReadUUID()
+	d.record(DebugEvent{Op: "ReadUUID", Value: value, Err: err})
+	return value, err
+}
+
+func (d *DebugFormat) Skip(typeID types.Type) error {
+	err := d.inner.Skip(typeID)
+	d.record(DebugEvent{Op: "Skip", Type: typeID, Err: err})
+	return err
+}
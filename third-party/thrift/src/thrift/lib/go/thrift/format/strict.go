@@ -0,0 +1,635 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package format holds types.Format decorators: wrappers that add
+// cross-cutting behavior around another Format implementation without it
+// needing to know about the wrapper.
+package format
+
+import (
+	"fmt"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift/types"
+)
+
+type frameKind byte
+
+const (
+	frameMessage frameKind = iota
+	frameStruct
+	frameField
+	frameMap
+	frameList
+	frameSet
+)
+
+func (k frameKind) String() string {
+	switch k {
+	case frameMessage:
+		return "Message"
+	case frameStruct:
+		return "Struct"
+	case frameField:
+		return "Field"
+	case frameMap:
+		return "Map"
+	case frameList:
+		return "List"
+	case frameSet:
+		return "Set"
+	default:
+		return "?"
+	}
+}
+
+// frame tracks one open Begin/End pair. For Field/List/Set, elemType is the
+// type every value produced in the frame must match and remaining counts
+// down to zero. For Map, keyType/elemType are the key/value types and
+// wantKey alternates which one is expected next.
+type frame struct {
+	kind      frameKind
+	keyType   types.Type
+	elemType  types.Type
+	remaining int
+	wantKey   bool
+}
+
+func (f frame) incomplete() bool {
+	switch f.kind {
+	case frameField, frameList, frameSet:
+		return f.remaining != 0
+	case frameMap:
+		return f.remaining != 0 || !f.wantKey
+	default:
+		return false
+	}
+}
+
+// strictFormat wraps a types.Format with an explicit stack of open
+// Message/Struct/Field/Map/List/Set frames, so that a mismatched Begin/End
+// pair, a container that produces more or fewer elements than it
+// declared, or a field value whose type doesn't match what WriteFieldBegin
+// (or the wire, on the read side) promised, surfaces as a
+// *types.ProtocolException instead of silently corrupting the stream.
+type strictFormat struct {
+	inner types.Format
+	stack []frame
+}
+
+// NewStrict wraps inner with state validation. It's meant for tests,
+// fuzzing, and catching generated-code bugs -- the bookkeeping isn't free,
+// so production code should use inner directly once it's trusted.
+func NewStrict(inner types.Format) types.Format {
+	return &strictFormat{inner: inner}
+}
+
+func mismatchErr(format string, args ...interface{}) error {
+	return types.NewProtocolException(types.INVALID_DATA, "strict: "+fmt.Sprintf(format, args...))
+}
+
+func (s *strictFormat) push(f frame) {
+	s.stack = append(s.stack, f)
+}
+
+// pop validates and removes the top frame, which must be of kind want.
+func (s *strictFormat) pop(want frameKind) error {
+	if len(s.stack) == 0 {
+		return mismatchErr("unmatched %v End", want)
+	}
+	top := s.stack[len(s.stack)-1]
+	if top.kind != want {
+		return mismatchErr("%v End called while a %v frame is open", want, top.kind)
+	}
+	if top.incomplete() {
+		return mismatchErr("%v ended with its declared element count not yet satisfied", top.kind)
+	}
+	s.stack = s.stack[:len(s.stack)-1]
+	return nil
+}
+
+// enterValue is called immediately before producing (reading or writing)
+// a value of type t. If the innermost open frame is a Field, List, Set, or
+// Map, it validates t against what that frame declared and advances the
+// frame's bookkeeping. It is a no-op at the top level or inside a Struct
+// or Message frame, since those don't constrain the type of what comes
+// next.
+func (s *strictFormat) enterValue(t types.Type) error {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	top := &s.stack[len(s.stack)-1]
+	switch top.kind {
+	case frameField:
+		if top.remaining == 0 {
+			return mismatchErr("more than one value produced for a single field")
+		}
+		if top.elemType != t {
+			return mismatchErr("field declared type %v but got a value of type %v", top.elemType, t)
+		}
+		top.remaining--
+	case frameList, frameSet:
+		if top.remaining == 0 {
+			return mismatchErr("%v produced more elements than its declared size", top.kind)
+		}
+		if top.elemType != t {
+			return mismatchErr("%v declared element type %v but got a value of type %v", top.kind, top.elemType, t)
+		}
+		top.remaining--
+	case frameMap:
+		want := top.elemType
+		if top.wantKey {
+			want = top.keyType
+		}
+		if top.remaining == 0 {
+			return mismatchErr("map produced more entries than its declared size")
+		}
+		if want != t {
+			return mismatchErr("map declared %v but got a value of type %v", want, t)
+		}
+		if top.wantKey {
+			top.wantKey = false
+		} else {
+			top.wantKey = true
+			top.remaining--
+		}
+	}
+	return nil
+}
+
+func (s *strictFormat) Flush() error { return s.inner.Flush() }
+
+func (s *strictFormat) SetSkipOptions(opts types.SkipOptions) { s.inner.SetSkipOptions(opts) }
+
+func (s *strictFormat) WriteMessageBegin(name string, typeID byte, seqID int32) error {
+	if err := s.inner.WriteMessageBegin(name, typeID, seqID); err != nil {
+		return err
+	}
+	s.push(frame{kind: frameMessage})
+	return nil
+}
+
+func (s *strictFormat) WriteMessageEnd() error {
+	if err := s.pop(frameMessage); err != nil {
+		return err
+	}
+	return s.inner.WriteMessageEnd()
+}
+
+func (s *strictFormat) WriteStructBegin(name string) error {
+	if err := s.enterValue(types.STRUCT); err != nil {
+		return err
+	}
+	if err := s.inner.WriteStructBegin(name); err != nil {
+		return err
+	}
+	s.push(frame{kind: frameStruct})
+	return nil
+}
+
+func (s *strictFormat) WriteStructEnd() error {
+	if err := s.pop(frameStruct); err != nil {
+		return err
+	}
+	return s.inner.WriteStructEnd()
+}
+
+func (s *strictFormat) WriteFieldBegin(name string, typeID types.Type, id int16) error {
+	if len(s.stack) == 0 || s.stack[len(s.stack)-1].kind != frameStruct {
+		return mismatchErr("WriteFieldBegin called outside of a struct")
+	}
+	if err := s.inner.WriteFieldBegin(name, typeID, id); err != nil {
+		return err
+	}
+	s.push(frame{kind: frameField, elemType: typeID, remaining: 1})
+	return nil
+}
+
+func (s *strictFormat) WriteFieldEnd() error {
+	if err := s.pop(frameField); err != nil {
+		return err
+	}
+	return s.inner.WriteFieldEnd()
+}
+
+func (s *strictFormat) WriteFieldStop() error {
+	if len(s.stack) == 0 || s.stack[len(s.stack)-1].kind != frameStruct {
+		return mismatchErr("WriteFieldStop called outside of a struct")
+	}
+	return s.inner.WriteFieldStop()
+}
+
+func (s *strictFormat) WriteMapBegin(keyType, valueType types.Type, size int) error {
+	if err := s.enterValue(types.MAP); err != nil {
+		return err
+	}
+	if err := s.inner.WriteMapBegin(keyType, valueType, size); err != nil {
+		return err
+	}
+	s.push(frame{kind: frameMap, keyType: keyType, elemType: valueType, remaining: size, wantKey: true})
+	return nil
+}
+
+func (s *strictFormat) WriteMapEnd() error {
+	if err := s.pop(frameMap); err != nil {
+		return err
+	}
+	return s.inner.WriteMapEnd()
+}
+
+func (s *strictFormat) WriteListBegin(elemType types.Type, size int) error {
+	if err := s.enterValue(types.LIST); err != nil {
+		return err
+	}
+	if err := s.inner.WriteListBegin(elemType, size); err != nil {
+		return err
+	}
+	s.push(frame{kind: frameList, elemType: elemType, remaining: size})
+	return nil
+}
+
+func (s *strictFormat) WriteListEnd() error {
+	if err := s.pop(frameList); err != nil {
+		return err
+	}
+	return s.inner.WriteListEnd()
+}
+
+func (s *strictFormat) WriteSetBegin(elemType types.Type, size int) error {
+	if err := s.enterValue(types.SET); err != nil {
+		return err
+	}
+	if err := s.inner.WriteSetBegin(elemType, size); err != nil {
+		return err
+	}
+	s.push(frame{kind: frameSet, elemType: elemType, remaining: size})
+	return nil
+}
+
+func (s *strictFormat) WriteSetEnd() error {
+	if err := s.pop(frameSet); err != nil {
+		return err
+	}
+	return s.inner.WriteSetEnd()
+}
+
+func (s *strictFormat) WriteBool(value bool) error {
+	if err := s.enterValue(types.BOOL); err != nil {
+		return err
+	}
+	return s.inner.WriteBool(value)
+}
+
+func (s *strictFormat) WriteByte(value byte) error {
+	if err := s.enterValue(types.BYTE); err != nil {
+		return err
+	}
+	return s.inner.WriteByte(value)
+}
+
+func (s *strictFormat) WriteI16(value int16) error {
+	if err := s.enterValue(types.I16); err != nil {
+		return err
+	}
+	return s.inner.WriteI16(value)
+}
+
+func (s *strictFormat) WriteI32(value int32) error {
+	if err := s.enterValue(types.I32); err != nil {
+		return err
+	}
+	return s.inner.WriteI32(value)
+}
+
+func (s *strictFormat) WriteI64(value int64) error {
+	if err := s.enterValue(types.I64); err != nil {
+		return err
+	}
+	return s.inner.WriteI64(value)
+}
+
+func (s *strictFormat) WriteDouble(value float64) error {
+	if err := s.enterValue(types.DOUBLE); err != nil {
+		return err
+	}
+	return s.inner.WriteDouble(value)
+}
+
+func (s *strictFormat) WriteFloat(value float32) error {
+	if err := s.enterValue(types.FLOAT); err != nil {
+		return err
+	}
+	return s.inner.WriteFloat(value)
+}
+
+func (s *strictFormat) WriteString(value string) error {
+	if err := s.enterValue(types.STRING); err != nil {
+		return err
+	}
+	return s.inner.WriteString(value)
+}
+
+func (s *strictFormat) WriteBinary(value []byte) error {
+	if err := s.enterValue(types.STRING); err != nil {
+		return err
+	}
+	return s.inner.WriteBinary(value)
+}
+
+func (s *strictFormat) WriteUUID(value types.UUID) error {
+	if err := s.enterValue(types.TYPE_UUID); err != nil {
+		return err
+	}
+	return s.inner.WriteUUID(value)
+}
+
+func (s *strictFormat) ReadMessageBegin() (string, byte, int32, error) {
+	name, typeID, seqID, err := s.inner.ReadMessageBegin()
+	if err != nil {
+		return name, typeID, seqID, err
+	}
+	s.push(frame{kind: frameMessage})
+	return name, typeID, seqID, nil
+}
+
+func (s *strictFormat) ReadMessageEnd() error {
+	if err := s.pop(frameMessage); err != nil {
+		return err
+	}
+	return s.inner.ReadMessageEnd()
+}
+
+func (s *strictFormat) ReadStructBegin() (string, error) {
+	if err := s.enterValue(types.STRUCT); err != nil {
+		return "", err
+	}
+	name, err := s.inner.ReadStructBegin()
+	if err != nil {
+		return name, err
+	}
+	s.push(frame{kind: frameStruct})
+	return name, nil
+}
+
+func (s *strictFormat) ReadStructEnd() error {
+	if err := s.pop(frameStruct); err != nil {
+		return err
+	}
+	return s.inner.ReadStructEnd()
+}
+
+func (s *strictFormat) ReadFieldBegin() (string, types.Type, int16, error) {
+	if len(s.stack) == 0 || s.stack[len(s.stack)-1].kind != frameStruct {
+		return "", 0, 0, mismatchErr("ReadFieldBegin called outside of a struct")
+	}
+	name, typeID, id, err := s.inner.ReadFieldBegin()
+	if err != nil {
+		return name, typeID, id, err
+	}
+	if typeID != types.STOP {
+		s.push(frame{kind: frameField, elemType: typeID, remaining: 1})
+	}
+	return name, typeID, id, nil
+}
+
+func (s *strictFormat) ReadFieldEnd() error {
+	if err := s.pop(frameField); err != nil {
+		return err
+	}
+	return s.inner.ReadFieldEnd()
+}
+
+func (s *strictFormat) ReadMapBegin() (types.Type, types.Type, int, error) {
+	if err := s.enterValue(types.MAP); err != nil {
+		return 0, 0, 0, err
+	}
+	keyType, valueType, size, err := s.inner.ReadMapBegin()
+	if err != nil {
+		return keyType, valueType, size, err
+	}
+	s.push(frame{kind: frameMap, keyType: keyType, elemType: valueType, remaining: size, wantKey: true})
+	return keyType, valueType, size, nil
+}
+
+func (s *strictFormat) ReadMapEnd() error {
+	if err := s.pop(frameMap); err != nil {
+		return err
+	}
+	return s.inner.ReadMapEnd()
+}
+
+func (s *strictFormat) ReadListBegin() (types.Type, int, error) {
+	if err := s.enterValue(types.LIST); err != nil {
+		return 0, 0, err
+	}
+	elemType, size, err := s.inner.ReadListBegin()
+	if err != nil {
+		return elemType, size, err
+	}
+	s.push(frame{kind: frameList, elemType: elemType, remaining: size})
+	return elemType, size, nil
+}
+
+func (s *strictFormat) ReadListEnd() error {
+	if err := s.pop(frameList); err != nil {
+		return err
+	}
+	return s.inner.ReadListEnd()
+}
+
+func (s *strictFormat) ReadSetBegin() (types.Type, int, error) {
+	if err := s.enterValue(types.SET); err != nil {
+		return 0, 0, err
+	}
+	elemType, size, err := s.inner.ReadSetBegin()
+	if err != nil {
+		return elemType, size, err
+	}
+	s.push(frame{kind: frameSet, elemType: elemType, remaining: size})
+	return elemType, size, nil
+}
+
+func (s *strictFormat) ReadSetEnd() error {
+	if err := s.pop(frameSet); err != nil {
+		return err
+	}
+	return s.inner.ReadSetEnd()
+}
+
+func (s *strictFormat) ReadBool() (bool, error) {
+	if err := s.enterValue(types.BOOL); err != nil {
+		return false, err
+	}
+	return s.inner.ReadBool()
+}
+
+func (s *strictFormat) ReadByte() (byte, error) {
+	if err := s.enterValue(types.BYTE); err != nil {
+		return 0, err
+	}
+	return s.inner.ReadByte()
+}
+
+func (s *strictFormat) ReadI16() (int16, error) {
+	if err := s.enterValue(types.I16); err != nil {
+		return 0, err
+	}
+	return s.inner.ReadI16()
+}
+
+func (s *strictFormat) ReadI32() (int32, error) {
+	if err := s.enterValue(types.I32); err != nil {
+		return 0, err
+	}
+	return s.inner.ReadI32()
+}
+
+func (s *strictFormat) ReadI64() (int64, error) {
+	if err := s.enterValue(types.I64); err != nil {
+		return 0, err
+	}
+	return s.inner.ReadI64()
+}
+
+func (s *strictFormat) ReadDouble() (float64, error) {
+	if err := s.enterValue(types.DOUBLE); err != nil {
+		return 0, err
+	}
+	return s.inner.ReadDouble()
+}
+
+func (s *strictFormat) ReadFloat() (float32, error) {
+	if err := s.enterValue(types.FLOAT); err != nil {
+		return 0, err
+	}
+	return s.inner.ReadFloat()
+}
+
+func (s *strictFormat) ReadString() (string, error) {
+	if err := s.enterValue(types.STRING); err != nil {
+		return "", err
+	}
+	return s.inner.ReadString()
+}
+
+func (s *strictFormat) ReadBinary() ([]byte, error) {
+	if err := s.enterValue(types.STRING); err != nil {
+		return nil, err
+	}
+	return s.inner.ReadBinary()
+}
+
+func (s *strictFormat) ReadUUID() (types.UUID, error) {
+	if err := s.enterValue(types.TYPE_UUID); err != nil {
+		return types.UUID{}, err
+	}
+	return s.inner.ReadUUID()
+}
+
+// Skip reads and discards one value of typeID, routing every nested
+// Read call back through s so skipped structs/containers are validated
+// exactly as if the caller had read them field by field.
+func (s *strictFormat) Skip(typeID types.Type) error {
+	return skipValue(s, typeID)
+}
+
+func skipValue(f types.Format, typeID types.Type) error {
+	switch typeID {
+	case types.BOOL:
+		_, err := f.ReadBool()
+		return err
+	case types.BYTE:
+		_, err := f.ReadByte()
+		return err
+	case types.I16:
+		_, err := f.ReadI16()
+		return err
+	case types.I32:
+		_, err := f.ReadI32()
+		return err
+	case types.I64:
+		_, err := f.ReadI64()
+		return err
+	case types.DOUBLE:
+		_, err := f.ReadDouble()
+		return err
+	case types.FLOAT:
+		_, err := f.ReadFloat()
+		return err
+	case types.STRING:
+		_, err := f.ReadBinary()
+		return err
+	case types.TYPE_UUID:
+		_, err := f.ReadUUID()
+		return err
+	case types.STRUCT:
+		if _, err := f.ReadStructBegin(); err != nil {
+			return err
+		}
+		for {
+			_, fieldType, _, err := f.ReadFieldBegin()
+			if err != nil {
+				return err
+			}
+			if fieldType == types.STOP {
+				break
+			}
+			if err := skipValue(f, fieldType); err != nil {
+				return err
+			}
+			if err := f.ReadFieldEnd(); err != nil {
+				return err
+			}
+		}
+		return f.ReadStructEnd()
+	case types.MAP:
+		keyType, valueType, size, err := f.ReadMapBegin()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			if err := skipValue(f, keyType); err != nil {
+				return err
+			}
+			if err := skipValue(f, valueType); err != nil {
+				return err
+			}
+		}
+		return f.ReadMapEnd()
+	case types.SET, types.LIST:
+		var elemType types.Type
+		var size int
+		var err error
+		if typeID == types.SET {
+			elemType, size, err = f.ReadSetBegin()
+		} else {
+			elemType, size, err = f.ReadListBegin()
+		}
+		if err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			if err := skipValue(f, elemType); err != nil {
+				return err
+			}
+		}
+		if typeID == types.SET {
+			return f.ReadSetEnd()
+		}
+		return f.ReadListEnd()
+	default:
+		return types.NewProtocolException(types.INVALID_DATA, "strict: cannot skip unknown type")
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// framedTransport wraps an underlying Transport, prefixing every Flush with
+// a 4-byte big-endian frame length and reading frames the same way.
+type framedTransport struct {
+	transport   io.ReadWriteCloser
+	writeBuffer *bytes.Buffer
+	frame       []byte
+	frameOff    int
+
+	// MaxFrameSize bounds the frame length a peer is allowed to declare.
+	// Zero means DefaultMaxFrameSize.
+	MaxFrameSize int32
+}
+
+func newFramedTransport(transport io.ReadWriteCloser) *framedTransport {
+	return &framedTransport{
+		transport: transport,
+	}
+}
+
+func (t *framedTransport) maxFrameSize() int32 {
+	if t.MaxFrameSize <= 0 {
+		return DefaultMaxFrameSize
+	}
+	return t.MaxFrameSize
+}
+
+// acquireWriteBuffer lazily pulls a frame-assembly buffer from
+// framedWriteBufferPool; it is returned as soon as Flush is done with it so
+// a framedTransport never holds a buffer between calls, the same pattern
+// httpClient uses for its request buffer.
+func (t *framedTransport) acquireWriteBuffer() *bytes.Buffer {
+	if t.writeBuffer == nil {
+		t.writeBuffer = framedWriteBufferPool.Get()
+	}
+	return t.writeBuffer
+}
+
+func (t *framedTransport) releaseWriteBuffer() {
+	if t.writeBuffer != nil {
+		framedWriteBufferPool.Put(t.writeBuffer)
+		t.writeBuffer = nil
+	}
+}
+
+func (t *framedTransport) Write(b []byte) (int, error) {
+	return t.acquireWriteBuffer().Write(b)
+}
+
+func (t *framedTransport) Flush() error {
+	defer t.releaseWriteBuffer()
+	buf := t.acquireWriteBuffer()
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(buf.Len()))
+	if _, err := t.transport.Write(hdr[:]); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	if _, err := t.transport.Write(buf.Bytes()); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	if f, ok := t.transport.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// readFrame reads the next length-prefixed frame into t.frame. If the
+// declared size exceeds MaxFrameSize, it returns a TransportException
+// before allocating t.frame, and the underlying reader is wrapped in an
+// io.LimitReader for the body read so a lying length can't over-read
+// either.
+func (t *framedTransport) readFrame() error {
+	var hdr [4]byte
+	if _, err := io.ReadFull(t.transport, hdr[:]); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	size := binary.BigEndian.Uint32(hdr[:])
+	if size > uint32(t.maxFrameSize()) {
+		return NewTransportException(SIZE_LIMIT, "framedTransport: frame size exceeds MaxFrameSize")
+	}
+	t.frame = make([]byte, size)
+	limited := io.LimitReader(t.transport, int64(size))
+	if _, err := io.ReadFull(limited, t.frame); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	t.frameOff = 0
+	return nil
+}
+
+func (t *framedTransport) Read(b []byte) (int, error) {
+	if t.frameOff >= len(t.frame) {
+		if err := t.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, t.frame[t.frameOff:])
+	t.frameOff += n
+	return n, nil
+}
+
+func (t *framedTransport) Close() error {
+	t.releaseWriteBuffer()
+	return t.transport.Close()
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import "github.com/facebook/fbthrift/thrift/lib/go/thrift/types"
+
+// HeaderProtocol is a types.Format that reads and writes its messages
+// through a headerTransport, delegating the actual value encoding to
+// whatever inner Format (binary, compact, ...) the two sides negotiated
+// via the header's protocol id.
+type HeaderProtocol struct {
+	types.Format
+	trans *headerTransport
+}
+
+// NewHeaderProtocol wraps trans with inner as the value-encoding Format.
+func NewHeaderProtocol(trans *headerTransport, inner types.Format) *HeaderProtocol {
+	return &HeaderProtocol{Format: inner, trans: trans}
+}
+
+// SetHeader sets a key/value info header sent with the next frame only.
+func (p *HeaderProtocol) SetHeader(key, value string) {
+	p.trans.SetHeader(key, value)
+}
+
+// Header returns a key/value info header observed on the most recently
+// read frame.
+func (p *HeaderProtocol) Header(key string) (string, bool) {
+	return p.trans.Header(key)
+}
+
+// SetPersistentHeader sets a key/value info header that is resent on every
+// subsequent frame written through this protocol.
+func (p *HeaderProtocol) SetPersistentHeader(key, value string) {
+	p.trans.SetPersistentHeader(key, value)
+}
+
+// PersistentHeaders returns a copy of the currently configured persistent
+// headers.
+func (p *HeaderProtocol) PersistentHeaders() map[string]string {
+	return p.trans.PersistentHeaders()
+}
+
+// ClearPersistentHeaders removes all persistent headers.
+func (p *HeaderProtocol) ClearPersistentHeaders() {
+	p.trans.ClearPersistentHeaders()
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Transform ids as defined by the THeader spec. TransformZlib is the only
+// one implemented directly by headerTransport; everything else is resolved
+// through the registry below so additional codecs can be plugged in without
+// modifying this package (see THRIFT-4612 for the original none/zlib-only
+// rollout).
+const (
+	TransformNone   uint32 = 0x00
+	TransformZlib   uint32 = 0x01
+	TransformSnappy uint32 = 0x03
+	TransformZstd   uint32 = 0x05
+	TransformLZ4    uint32 = 0x06
+)
+
+// TransformReaderFactory wraps an underlying reader with a streaming
+// decompressor for a registered transform.
+type TransformReaderFactory func(io.Reader) (io.ReadCloser, error)
+
+// TransformWriterFactory wraps an underlying writer with a streaming
+// compressor for a registered transform.
+type TransformWriterFactory func(io.Writer) (io.WriteCloser, error)
+
+type transformCodec struct {
+	reader TransformReaderFactory
+	writer TransformWriterFactory
+}
+
+var (
+	transformRegistryMu sync.RWMutex
+	transformRegistry   = map[uint32]transformCodec{}
+)
+
+// RegisterTransform installs a THeader compression transform under id so it
+// can be selected with (*headerTransport).SetTransform and transparently
+// decoded on read. Registering under an id that is already registered
+// replaces the previous codec. This is typically called from an init()
+// func in the package providing the codec (e.g. a zstd or snappy wrapper).
+func RegisterTransform(id uint32, factory TransformReaderFactory, wfactory TransformWriterFactory) {
+	if factory == nil || wfactory == nil {
+		panic("thrift: RegisterTransform requires non-nil factories")
+	}
+	if id == TransformNone || id == TransformZlib {
+		panic("thrift: cannot override built-in transform")
+	}
+	transformRegistryMu.Lock()
+	defer transformRegistryMu.Unlock()
+	transformRegistry[id] = transformCodec{reader: factory, writer: wfactory}
+}
+
+func lookupTransform(id uint32) (transformCodec, bool) {
+	transformRegistryMu.RLock()
+	defer transformRegistryMu.RUnlock()
+	codec, ok := transformRegistry[id]
+	return codec, ok
+}
+
+// wrapTransformReader wraps r with the decompressor registered for id. It
+// returns a TransportException, not a generic error, so callers can
+// propagate it the same way as any other framing failure.
+func wrapTransformReader(id uint32, r io.Reader) (io.ReadCloser, error) {
+	codec, ok := lookupTransform(id)
+	if !ok {
+		return nil, NewTransportException(UNKNOWN_TRANSPORT_EXCEPTION, fmt.Sprintf("thrift: unknown header transform id %#x", id))
+	}
+	rc, err := codec.reader(r)
+	if err != nil {
+		return nil, NewTransportExceptionFromError(err)
+	}
+	return rc, nil
+}
+
+// wrapTransformWriter wraps w with the compressor registered for id.
+func wrapTransformWriter(id uint32, w io.Writer) (io.WriteCloser, error) {
+	codec, ok := lookupTransform(id)
+	if !ok {
+		return nil, NewTransportException(UNKNOWN_TRANSPORT_EXCEPTION, fmt.Sprintf("thrift: unknown header transform id %#x", id))
+	}
+	wc, err := codec.writer(w)
+	if err != nil {
+		return nil, NewTransportExceptionFromError(err)
+	}
+	return wc, nil
+}
+
+// SetTransform selects the transform applied to the outbound frame on the
+// next flush. id must be TransformNone, TransformZlib, or a transform
+// previously installed with RegisterTransform; any other value returns a
+// TransportException rather than silently falling back to TransformNone.
+func (t *headerTransport) SetTransform(id uint32) error {
+	if id != TransformNone && id != TransformZlib {
+		if _, ok := lookupTransform(id); !ok {
+			return NewTransportException(UNKNOWN_TRANSPORT_EXCEPTION, fmt.Sprintf("thrift: unknown header transform id %#x", id))
+		}
+	}
+	t.writeTransforms = []uint32{id}
+	return nil
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+func init() {
+	RegisterTransform(TransformSnappy, newSnappyReader, newSnappyWriter)
+}
+
+func newSnappyReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(snappy.NewReader(r)), nil
+}
+
+type snappyWriteCloser struct {
+	*snappy.Writer
+}
+
+func (s *snappyWriteCloser) Close() error {
+	return s.Writer.Close()
+}
+
+func newSnappyWriter(w io.Writer) (io.WriteCloser, error) {
+	return &snappyWriteCloser{Writer: snappy.NewBufferedWriter(w)}, nil
+}
@@ -0,0 +1,350 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+)
+
+// THeader info ids, as defined by the THeader spec.
+const (
+	infoIDKeyValue           uint32 = 1
+	infoIDPersistentKeyValue uint32 = 2
+)
+
+// headerTransport implements the THeader transport: a length-prefixed frame
+// whose payload starts with a small header block (protocol id, the chain of
+// transforms applied to the rest of the frame, and key/value info headers)
+// followed by the wrapped protocol payload. See THRIFT-4612 for the
+// original none/zlib-only rollout this package shipped with.
+type headerTransport struct {
+	transport io.ReadWriteCloser
+
+	writeBuffer       *bytes.Buffer
+	writeTransforms   []uint32
+	writeHeaders      map[string]string
+	persistentHeaders map[string]string
+
+	frame       []byte
+	frameOff    int
+	readHeaders map[string]string
+
+	// MaxFrameSize bounds the frame length a peer is allowed to declare.
+	// Zero means DefaultMaxFrameSize.
+	MaxFrameSize int32
+}
+
+func (t *headerTransport) maxFrameSize() int32 {
+	if t.MaxFrameSize <= 0 {
+		return DefaultMaxFrameSize
+	}
+	return t.MaxFrameSize
+}
+
+func newHeaderTransport(transport io.ReadWriteCloser) *headerTransport {
+	return &headerTransport{
+		transport:         transport,
+		writeBuffer:       headerWriteBufferPool.Get(),
+		writeHeaders:      make(map[string]string),
+		persistentHeaders: make(map[string]string),
+		readHeaders:       make(map[string]string),
+	}
+}
+
+// SetHeader sets a key/value info header sent with the next frame only.
+func (t *headerTransport) SetHeader(key, value string) {
+	t.writeHeaders[key] = value
+}
+
+// Header returns a key/value info header observed on the most recently
+// read frame.
+func (t *headerTransport) Header(key string) (string, bool) {
+	v, ok := t.readHeaders[key]
+	return v, ok
+}
+
+// SetPersistentHeader sets a key/value info header that is resent on every
+// subsequent frame, not just the next one, so the peer observes it without
+// the caller having to call SetHeader again before each request.
+func (t *headerTransport) SetPersistentHeader(key, value string) {
+	t.persistentHeaders[key] = value
+}
+
+// PersistentHeaders returns a copy of the currently configured persistent
+// headers.
+func (t *headerTransport) PersistentHeaders() map[string]string {
+	headers := make(map[string]string, len(t.persistentHeaders))
+	for k, v := range t.persistentHeaders {
+		headers[k] = v
+	}
+	return headers
+}
+
+// ClearPersistentHeaders removes all persistent headers; subsequent frames
+// no longer include them.
+func (t *headerTransport) ClearPersistentHeaders() {
+	t.persistentHeaders = make(map[string]string)
+}
+
+func (t *headerTransport) Write(b []byte) (int, error) {
+	return t.writeBuffer.Write(b)
+}
+
+func writeVarString(buf *bytes.Buffer, s string) {
+	var lenBuf [binary.MaxVarintLen32]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(s)))
+	buf.Write(lenBuf[:n])
+	buf.WriteString(s)
+}
+
+func readVarString(r *bytes.Reader) (string, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Flush compresses the buffered payload (if a transform was selected with
+// SetTransform), assembles the header block, and writes the full
+// length-prefixed frame.
+func (t *headerTransport) Flush() error {
+	transformID := TransformNone
+	if len(t.writeTransforms) > 0 {
+		transformID = t.writeTransforms[0]
+	}
+
+	payload := t.writeBuffer.Bytes()
+	switch transformID {
+	case TransformNone:
+		// no-op
+	case TransformZlib:
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(payload); err != nil {
+			return NewTransportExceptionFromError(err)
+		}
+		if err := zw.Close(); err != nil {
+			return NewTransportExceptionFromError(err)
+		}
+		payload = compressed.Bytes()
+	default:
+		var compressed bytes.Buffer
+		wc, err := wrapTransformWriter(transformID, &compressed)
+		if err != nil {
+			return err
+		}
+		if _, err := wc.Write(payload); err != nil {
+			return NewTransportExceptionFromError(err)
+		}
+		if err := wc.Close(); err != nil {
+			return NewTransportExceptionFromError(err)
+		}
+		payload = compressed.Bytes()
+	}
+
+	var header bytes.Buffer
+	// number of transforms, followed by each transform id
+	if transformID == TransformNone {
+		binary.Write(&header, binary.BigEndian, uint8(0))
+	} else {
+		binary.Write(&header, binary.BigEndian, uint8(1))
+		var idBuf [binary.MaxVarintLen32]byte
+		n := binary.PutUvarint(idBuf[:], uint64(transformID))
+		header.Write(idBuf[:n])
+	}
+
+	// info headers: info id, count, then key/value pairs. The persistent
+	// block is written first so a receiver that only looks at the first
+	// key_value-shaped block it finds still sees the persistent values.
+	writeInfoBlock := func(infoID uint32, headers map[string]string) {
+		if len(headers) == 0 {
+			return
+		}
+		binary.Write(&header, binary.BigEndian, uint8(infoID))
+		var cntBuf [binary.MaxVarintLen32]byte
+		n := binary.PutUvarint(cntBuf[:], uint64(len(headers)))
+		header.Write(cntBuf[:n])
+		for k, v := range headers {
+			writeVarString(&header, k)
+			writeVarString(&header, v)
+		}
+	}
+	writeInfoBlock(infoIDPersistentKeyValue, t.persistentHeaders)
+	writeInfoBlock(infoIDKeyValue, t.writeHeaders)
+	t.writeHeaders = make(map[string]string)
+
+	for header.Len()%4 != 0 {
+		header.WriteByte(0)
+	}
+
+	var frame bytes.Buffer
+	binary.Write(&frame, binary.BigEndian, uint16(0x0FFF)) // magic
+	binary.Write(&frame, binary.BigEndian, uint16(0))      // flags
+	binary.Write(&frame, binary.BigEndian, uint32(0))      // seq id
+	binary.Write(&frame, binary.BigEndian, uint16(header.Len()/4))
+	frame.Write(header.Bytes())
+	frame.Write(payload)
+
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(frame.Len()))
+	if _, err := t.transport.Write(lenBuf[:]); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	if _, err := t.transport.Write(frame.Bytes()); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	t.writeBuffer.Reset()
+	if f, ok := t.transport.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// ResetProtocol reads the next frame off the wire and prepares it to be
+// consumed by subsequent Reads. It is exported (rather than happening
+// transparently inside Read) because callers that swap protocols mid
+// connection need an explicit point to do so.
+func (t *headerTransport) ResetProtocol() error {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(t.transport, lenBuf[:]); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	size := binary.BigEndian.Uint32(lenBuf[:])
+	if size > uint32(t.maxFrameSize()) {
+		return NewTransportException(SIZE_LIMIT, "headerTransport: frame size exceeds MaxFrameSize")
+	}
+	raw := make([]byte, size)
+	limited := io.LimitReader(t.transport, int64(size))
+	if _, err := io.ReadFull(limited, raw); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+
+	r := bytes.NewReader(raw)
+	var magic, flags uint16
+	var seqID uint32
+	var headerWords uint16
+	binary.Read(r, binary.BigEndian, &magic)
+	binary.Read(r, binary.BigEndian, &flags)
+	binary.Read(r, binary.BigEndian, &seqID)
+	binary.Read(r, binary.BigEndian, &headerWords)
+
+	headerBytes := make([]byte, int(headerWords)*4)
+	if _, err := io.ReadFull(r, headerBytes); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	hr := bytes.NewReader(headerBytes)
+
+	var numTransforms uint8
+	binary.Read(hr, binary.BigEndian, &numTransforms)
+	// Chained transforms (numTransforms > 1) would need to be undone in
+	// reverse order, one bytes.Reader wrapping the next; this transport only
+	// ever writes a single transform (see Flush), so rather than silently
+	// decoding a chain by applying just the last id, reject anything we
+	// can't actually honor.
+	if numTransforms > 1 {
+		return NewTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "headerTransport: chained transforms are not supported")
+	}
+	var transformID uint32 = TransformNone
+	for i := uint8(0); i < numTransforms; i++ {
+		id, err := binary.ReadUvarint(hr)
+		if err != nil {
+			return NewTransportExceptionFromError(err)
+		}
+		transformID = uint32(id)
+	}
+
+	t.readHeaders = make(map[string]string)
+	for hr.Len() > 0 {
+		infoID, err := hr.ReadByte()
+		if err != nil {
+			break
+		}
+		if infoID == uint8(infoIDKeyValue) || infoID == uint8(infoIDPersistentKeyValue) {
+			count, err := binary.ReadUvarint(hr)
+			if err != nil {
+				return NewTransportExceptionFromError(err)
+			}
+			for i := uint64(0); i < count; i++ {
+				k, err := readVarString(hr)
+				if err != nil {
+					return NewTransportExceptionFromError(err)
+				}
+				v, err := readVarString(hr)
+				if err != nil {
+					return NewTransportExceptionFromError(err)
+				}
+				t.readHeaders[k] = v
+			}
+		}
+	}
+
+	const fixedHeaderLen = 2 + 2 + 4 + 2 // magic + flags + seqid + headerWords
+	payload := raw[fixedHeaderLen+len(headerBytes):]
+	switch transformID {
+	case TransformNone:
+		t.frame = payload
+	case TransformZlib:
+		zr, err := zlib.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return NewTransportExceptionFromError(err)
+		}
+		defer zr.Close()
+		decoded, err := io.ReadAll(zr)
+		if err != nil {
+			return NewTransportExceptionFromError(err)
+		}
+		t.frame = decoded
+	default:
+		rc, err := wrapTransformReader(transformID, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		decoded, err := io.ReadAll(rc)
+		if err != nil {
+			return NewTransportExceptionFromError(err)
+		}
+		t.frame = decoded
+	}
+	t.frameOff = 0
+	return nil
+}
+
+func (t *headerTransport) Read(b []byte) (int, error) {
+	if t.frameOff >= len(t.frame) {
+		if err := t.ResetProtocol(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, t.frame[t.frameOff:])
+	t.frameOff += n
+	return n, nil
+}
+
+func (t *headerTransport) Close() error {
+	headerWriteBufferPool.Put(t.writeBuffer)
+	t.writeBuffer = nil
+	return t.transport.Close()
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Additional TransportExceptionTypes surfaced by the HTTP/2 client so
+// callers can tell "server told us to go away and retry elsewhere" apart
+// from a plain framing or network failure.
+const (
+	HTTP2_GOAWAY       TransportExceptionType = 7
+	HTTP2_RESET_STREAM TransportExceptionType = 8
+)
+
+// HTTP2ClientOptions configures the *http2.Transport used by
+// newHTTP2Client. Set Transport to a *http2.Transport shared across
+// multiple newHTTP2Client calls so they multiplex RPCs as concurrent
+// streams over one underlying TLS/h2c connection instead of opening one
+// connection per call the way HTTP/1.1 does; leave it nil to have one
+// built from the remaining fields (which, unlike Transport, is not shared
+// automatically -- callers that want sharing without constructing their
+// own *http2.Transport should build one from these fields once and pass
+// it via Transport on every call).
+type HTTP2ClientOptions struct {
+	// Transport, if set, is used as-is and all other fields are ignored.
+	// This is the only way to share one connection/stream pool across
+	// multiple newHTTP2Client calls.
+	Transport *http2.Transport
+	// IdlePingInterval is how often an idle connection is pinged to check
+	// it's still alive; wired to http2.Transport.ReadIdleTimeout.
+	IdlePingInterval time.Duration
+	// AllowH2C enables HTTP/2 over a plaintext connection (h2c), for
+	// talking to a server that doesn't terminate TLS.
+	AllowH2C bool
+	// TLSClientConfig, if set, is used for the TLS handshake on non-H2C
+	// connections -- including ALPN protocol selection via its NextProtos.
+	TLSClientConfig *tls.Config
+}
+
+func (o HTTP2ClientOptions) transport() *http2.Transport {
+	if o.Transport != nil {
+		return o.Transport
+	}
+	t := &http2.Transport{
+		AllowHTTP:       o.AllowH2C,
+		ReadIdleTimeout: o.IdlePingInterval,
+		TLSClientConfig: o.TLSClientConfig,
+	}
+	if o.AllowH2C {
+		// http2.Transport dials TLS unless told otherwise; h2c needs a
+		// plain TCP dial, as in NewHTTPClientWithOptions.
+		t.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		}
+	}
+	return t
+}
+
+// newHTTP2Client is the HTTP/2 sibling of newHTTPPostClient: it POSTs the
+// Thrift payload and reads the reply the same way, but when given an
+// HTTP2ClientOptions.Transport shared across multiple clients, the
+// underlying connection and its stream multiplexing are shared too, so
+// many concurrent Thrift calls don't serialize behind each other the way
+// repeated HTTP/1.1 requests would.
+func newHTTP2Client(url string, opts HTTP2ClientOptions) (*httpClient, error) {
+	c, err := newHTTPPostClient(url)
+	if err != nil {
+		return nil, err
+	}
+	c.client.Transport = opts.transport()
+	return c, nil
+}
+
+// classifyHTTP2Error turns an http2-specific error into a typed
+// TransportException: GOAWAY becomes HTTP2_GOAWAY (retry on a different
+// connection), RST_STREAM becomes HTTP2_RESET_STREAM (this stream's
+// framing broke, but the connection is still usable), anything else falls
+// back to the generic classification in NewTransportExceptionFromError.
+func classifyHTTP2Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	var goAway http2.GoAwayError
+	if errors.As(err, &goAway) {
+		return NewTransportException(HTTP2_GOAWAY, goAway.Error())
+	}
+	var streamErr http2.StreamError
+	if errors.As(err, &streamErr) {
+		return NewTransportException(HTTP2_RESET_STREAM, streamErr.Error())
+	}
+	return NewTransportExceptionFromError(err)
+}
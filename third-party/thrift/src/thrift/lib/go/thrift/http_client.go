@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http2"
+)
+
+// httpClient is a Transport that sends each Flush as one HTTP POST and
+// treats the response body as the data available to the next Read.
+type httpClient struct {
+	client        *http.Client
+	url           string
+	header        http.Header
+	requestBuffer *bytes.Buffer
+	response      *http.Response
+	extraCookies  []*http.Cookie
+}
+
+// newHTTPPostClient creates an httpClient that POSTs to url using its own
+// zero-value http.Client (and therefore http.DefaultTransport).
+func newHTTPPostClient(url string) (*httpClient, error) {
+	return &httpClient{
+		client: &http.Client{},
+		url:    url,
+		header: make(http.Header),
+	}, nil
+}
+
+// HTTPClientOptions configures the transport used by
+// NewHTTPClientWithOptions.
+type HTTPClientOptions struct {
+	// ForceHTTP2 makes the client negotiate HTTP/2 over TLS (ALPN) rather
+	// than letting net/http fall back to HTTP/1.1.
+	ForceHTTP2 bool
+	// AllowH2C enables HTTP/2 over a plaintext connection (h2c), for
+	// talking to a server that doesn't terminate TLS.
+	AllowH2C bool
+	// Transport, if set, is used as-is instead of constructing one from
+	// ForceHTTP2/AllowH2C. Useful for sharing connection/stream pooling and
+	// other *http2.Transport tuning across many httpClients.
+	Transport *http2.Transport
+}
+
+// NewHTTPClientWithOptions creates an httpClient like newHTTPPostClient,
+// additionally wiring golang.org/x/net/http2 into the underlying
+// http.Client when requested. A single long-lived connection can then
+// multiplex many Thrift RPCs as concurrent HTTP/2 streams instead of
+// serializing them one-request-per-connection over HTTP/1.1.
+func NewHTTPClientWithOptions(url string, opts HTTPClientOptions) (*httpClient, error) {
+	c := &httpClient{
+		client: &http.Client{},
+		url:    url,
+		header: make(http.Header),
+	}
+
+	switch {
+	case opts.Transport != nil:
+		c.client.Transport = opts.Transport
+	case opts.AllowH2C:
+		c.client.Transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+	case opts.ForceHTTP2:
+		c.client.Transport = &http2.Transport{}
+	}
+
+	return c, nil
+}
+
+// acquireRequestBuffer lazily pulls a buffer from httpRequestBufferPool the
+// first time this client writes. It is released back to the pool as soon
+// as Flush (or Close, if Flush was never called) is done with it, so an
+// httpClient never holds a pooled buffer between calls -- modeled on the
+// sync.Pool headerTransport uses for its own write buffer (THRIFT-5490).
+func (c *httpClient) acquireRequestBuffer() *bytes.Buffer {
+	if c.requestBuffer == nil {
+		c.requestBuffer = httpRequestBufferPool.Get()
+	}
+	return c.requestBuffer
+}
+
+func (c *httpClient) releaseRequestBuffer() {
+	if c.requestBuffer != nil {
+		httpRequestBufferPool.Put(c.requestBuffer)
+		c.requestBuffer = nil
+	}
+}
+
+// SetCookieJar installs jar on the underlying http.Client so cookies set by
+// the server (e.g. by an auth gateway fronting Thrift-over-HTTP) persist
+// across subsequent requests on this client.
+func (c *httpClient) SetCookieJar(jar http.CookieJar) {
+	c.client.Jar = jar
+}
+
+// AddCookie attaches an additional cookie to the next request, on top of
+// whatever the CookieJar (if any) would send.
+func (c *httpClient) AddCookie(cookie *http.Cookie) {
+	c.extraCookies = append(c.extraCookies, cookie)
+}
+
+// Cookies returns the cookies the jar holds for this client's URL, or nil
+// if no jar has been set.
+func (c *httpClient) Cookies() []*http.Cookie {
+	if c.client.Jar == nil {
+		return nil
+	}
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return nil
+	}
+	return c.client.Jar.Cookies(u)
+}
+
+// SetHeader sets an HTTP header sent with every subsequent request.
+func (c *httpClient) SetHeader(key, value string) {
+	c.header.Set(key, value)
+}
+
+// GetHeader returns a header value from the most recent response, or the
+// empty string if it wasn't present.
+func (c *httpClient) GetHeader(key string) string {
+	if c.response == nil {
+		return ""
+	}
+	return c.response.Header.Get(key)
+}
+
+// Write buffers b to be sent on the next Flush.
+func (c *httpClient) Write(b []byte) (int, error) {
+	return c.acquireRequestBuffer().Write(b)
+}
+
+// Flush POSTs the buffered request body and stores the response for
+// subsequent Reads.
+func (c *httpClient) Flush() error {
+	defer c.releaseRequestBuffer()
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(c.acquireRequestBuffer().Bytes()))
+	if err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	req.Header = c.header.Clone()
+	for _, cookie := range c.extraCookies {
+		req.AddCookie(cookie)
+	}
+	c.extraCookies = nil
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return classifyHTTP2Error(err)
+	}
+	c.response = resp
+	return nil
+}
+
+// Read reads from the body of the most recent response.
+func (c *httpClient) Read(b []byte) (int, error) {
+	if c.response == nil {
+		return 0, NewTransportException(NOT_OPEN, "httpClient: no response to read from")
+	}
+	n, err := c.response.Body.Read(b)
+	if err != nil && err != io.EOF {
+		err = NewTransportExceptionFromError(err)
+	}
+	return n, err
+}
+
+// Close releases the most recent response body, if any, and returns any
+// still-acquired request buffer to the pool.
+func (c *httpClient) Close() error {
+	c.releaseRequestBuffer()
+	if c.response != nil {
+		err := c.response.Body.Close()
+		c.response = nil
+		return err
+	}
+	return nil
+}
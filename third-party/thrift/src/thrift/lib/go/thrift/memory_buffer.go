@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import "bytes"
+
+// MemoryBuffer is an in-memory Transport backed by a bytes.Buffer. It is
+// mainly used by tests that need a Transport without a real connection.
+type MemoryBuffer struct {
+	*bytes.Buffer
+}
+
+// NewMemoryBuffer creates a MemoryBuffer with the default initial capacity.
+func NewMemoryBuffer() *MemoryBuffer {
+	return &MemoryBuffer{Buffer: &bytes.Buffer{}}
+}
+
+// NewMemoryBufferLen creates a MemoryBuffer with the given initial capacity.
+func NewMemoryBufferLen(size int) *MemoryBuffer {
+	buf := make([]byte, 0, size)
+	return &MemoryBuffer{Buffer: bytes.NewBuffer(buf)}
+}
+
+// Flush is a no-op: writes to a MemoryBuffer are visible immediately.
+func (p *MemoryBuffer) Flush() error { return nil }
+
+// Close resets the buffer, discarding any buffered data.
+func (p *MemoryBuffer) Close() error {
+	p.Buffer.Reset()
+	return nil
+}
@@ -17,15 +17,30 @@
 package thrift
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"golang.org/x/net/http2"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift/format"
+
 	"github.com/facebook/fbthrift/thrift/lib/go/thrift/types"
 )
 
@@ -44,16 +59,22 @@ type structData struct {
 }
 
 var (
-	data           string // test data for writing
-	protocolBdata  []byte // test data for writing; same as data
-	boolValues     = []bool{false, true, false, false, true}
-	byteValues     = []byte{117, 0, 1, 32, 127, 128, 255}
-	int16Values    = []int16{459, 0, 1, -1, -128, 127, 32767, -32768}
-	int32Values    = []int32{459, 0, 1, -1, -128, 127, 32767, 2147483647, -2147483535}
-	int64Values    = []int64{459, 0, 1, -1, -128, 127, 32767, 2147483647, -2147483535, 34359738481, -35184372088719, -9223372036854775808, 9223372036854775807}
-	doubleValues   = []float64{459.3, 0.0, -1.0, 1.0, 0.5, 0.3333, 3.14159, 1.537e-38, 1.673e25, 6.02214179e23, -6.02214179e23, INFINITY.Float64(), NEGATIVE_INFINITY.Float64(), NAN.Float64()}
-	floatValues    = []float32{459.3, 0.0, -1.0, 1.0, 0.5, 0.3333, 3.14159, 1.537e-38, 1.673e25, 6.02214179e23, -6.02214179e23, INFINITY.Float32(), NEGATIVE_INFINITY.Float32(), NAN.Float32()}
-	stringValues   = []string{"", "a", "st[uf]f", "st,u:ff with spaces", "stuff\twith\nescape\\characters'...\"lots{of}fun</xml>"}
+	data          string // test data for writing
+	protocolBdata []byte // test data for writing; same as data
+	boolValues    = []bool{false, true, false, false, true}
+	byteValues    = []byte{117, 0, 1, 32, 127, 128, 255}
+	int16Values   = []int16{459, 0, 1, -1, -128, 127, 32767, -32768}
+	int32Values   = []int32{459, 0, 1, -1, -128, 127, 32767, 2147483647, -2147483535}
+	int64Values   = []int64{459, 0, 1, -1, -128, 127, 32767, 2147483647, -2147483535, 34359738481, -35184372088719, -9223372036854775808, 9223372036854775807}
+	doubleValues  = []float64{459.3, 0.0, -1.0, 1.0, 0.5, 0.3333, 3.14159, 1.537e-38, 1.673e25, 6.02214179e23, -6.02214179e23, INFINITY.Float64(), NEGATIVE_INFINITY.Float64(), NAN.Float64()}
+	floatValues   = []float32{459.3, 0.0, -1.0, 1.0, 0.5, 0.3333, 3.14159, 1.537e-38, 1.673e25, 6.02214179e23, -6.02214179e23, INFINITY.Float32(), NEGATIVE_INFINITY.Float32(), NAN.Float32()}
+	stringValues  = []string{"", "a", "st[uf]f", "st,u:ff with spaces", "stuff\twith\nescape\\characters'...\"lots{of}fun</xml>"}
+	UUID_VALUES   = []types.UUID{
+		types.MustParseUUID("00000000-0000-0000-0000-000000000000"),
+		types.MustParseUUID("12345678-1234-5678-1234-567812345678"),
+		types.MustParseUUID("ffffffff-ffff-ffff-ffff-ffffffffffff"),
+		types.MustParseUUID("a1b2c3d4-e5f6-4789-abcd-ef0123456789"),
+	}
 	structTestData = structData{
 		name: "test struct",
 		fields: []fieldData{
@@ -69,6 +90,12 @@ var (
 				id:    2,
 				value: "hi",
 			},
+			{
+				name:  "field3",
+				typ:   types.TYPE_UUID,
+				id:    3,
+				value: UUID_VALUES[1],
+			},
 		},
 	}
 )
@@ -119,6 +146,87 @@ func HTTPClientSetupForTest(t *testing.T) net.Listener {
 	return l
 }
 
+// http2ServerSetupForTest stands up a TLS httptest server advertising h2
+// over ALPN and echoes the request body back as the response.
+func http2ServerSetupForTest(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(&HTTPEchoServer{})
+	server.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	server.StartTLS()
+	return server
+}
+
+// newHTTP2TransportForTest builds an *http2.Transport configured to trust
+// http2ServerSetupForTest's self-signed certificate. Share one instance
+// across multiple newHTTP2ClientForTest calls to multiplex them as
+// concurrent streams over one underlying connection; pass a fresh one to
+// keep them independent.
+func newHTTP2TransportForTest() *http2.Transport {
+	return &http2.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+}
+
+func newHTTP2ClientForTest(t *testing.T, url string, transport *http2.Transport) *httpClient {
+	c, err := newHTTP2Client(url, HTTP2ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatalf("newHTTP2Client: %s", err)
+	}
+	return c
+}
+
+// countingListener wraps a net.Listener, counting every connection it
+// accepts -- used below to prove a shared *http2.Transport really does
+// multiplex many concurrent Thrift RPCs over one TCP connection rather
+// than opening one per goroutine.
+type countingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+// ReadWriteHTTP2ProtocolParallelTest drives many goroutines' worth of
+// http2Client streams through ReadWriteStruct concurrently, all sharing
+// one *http2.Transport and therefore one underlying TLS connection. It
+// proves both that writes on distinct streams don't interleave at the
+// framing layer (every goroutine must read back exactly the struct it
+// wrote) and that the connection is actually shared (the server must only
+// ever accept one TCP connection).
+func ReadWriteHTTP2ProtocolParallelTest(t *testing.T, newFormat func(io.ReadWriteCloser) types.Format) {
+	server := httptest.NewUnstartedServer(&HTTPEchoServer{})
+	server.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	listener := &countingListener{Listener: server.Listener}
+	server.Listener = listener
+	server.StartTLS()
+	defer server.Close()
+
+	transport := newHTTP2TransportForTest()
+
+	const streams = 50
+	var wg sync.WaitGroup
+	for i := 0; i < streams; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := newHTTP2ClientForTest(t, server.URL, transport)
+			defer c.Close()
+			p := newFormat(c)
+			ReadWriteStruct(t, p, c)
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&listener.accepted); got != 1 {
+		t.Fatalf("ReadWriteHTTP2ProtocolParallelTest: server accepted %d connections for %d streams on one shared transport, want 1", got, streams)
+	}
+}
+
 func HTTPClientSetupForHeaderTest(t *testing.T) net.Listener {
 	l, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -170,6 +278,40 @@ type protocolTest func(t testing.TB, p types.Format, readWriter io.ReadWriter)
 type protocolReaderTest func(t testing.TB, p types.Format, reader io.Reader)
 type protocolWriterTest func(t testing.TB, p types.Format, writer io.Writer)
 
+// webSocketTransportSetupForTest dials a websocketTransport against an
+// httptest server that upgrades the connection and echoes each BINARY
+// message straight back, giving a blocking, full-duplex transport
+// suitable for ReadWriteProtocolParallelTest.
+func webSocketTransportSetupForTest(t *testing.T, opts WebSocketOptions) io.ReadWriteCloser {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trans, err := UpgradeWebSocket(w, r, opts)
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 65536)
+		for {
+			n, err := trans.Read(buf)
+			if err != nil {
+				return
+			}
+			if _, err := trans.Write(buf[:n]); err != nil {
+				return
+			}
+			if err := trans.Flush(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	client, err := DialWebSocket(wsURL, opts)
+	if err != nil {
+		t.Fatalf("DialWebSocket: %s", err)
+	}
+	return client
+}
+
 // ReadWriteProtocolParallelTest tests that a given protocol is safe to read
 // from and write to in different goroutines. This requires both a protocol
 // and a transport are only using shared state contained either within the set
@@ -178,7 +320,9 @@ type protocolWriterTest func(t testing.TB, p types.Format, writer io.Writer)
 // blocking reads and writes (socket, stream), since other golang Transport
 // implementations require that the data exists to be read when they are called (like bytes.Buffer)
 func ReadWriteProtocolParallelTest(t *testing.T, newFormat func(io.ReadWriteCloser) types.Format) {
-	transports := []func() io.ReadWriteCloser{}
+	transports := []func() io.ReadWriteCloser{
+		func() io.ReadWriteCloser { return webSocketTransportSetupForTest(t, WebSocketOptions{}) },
+	}
 	const iterations = 100
 
 	doForAllTransportsParallel := func(read protocolReaderTest, write protocolWriterTest) {
@@ -210,6 +354,7 @@ func ReadWriteProtocolParallelTest(t *testing.T, newFormat func(io.ReadWriteClos
 	doForAllTransportsParallel(ReadFloat, WriteFloat)
 	doForAllTransportsParallel(ReadString, WriteString)
 	doForAllTransportsParallel(ReadBinary, WriteBinary)
+	doForAllTransportsParallel(ReadUUID, WriteUUID)
 	doForAllTransportsParallel(ReadStruct, WriteStruct)
 
 	// perform set of many sequenced sets of reads and writes
@@ -242,6 +387,9 @@ func ReadWriteProtocolTest(t *testing.T, newFormat func(io.ReadWriteCloser) type
 	l := HTTPClientSetupForTest(t)
 	defer l.Close()
 
+	h2Server := http2ServerSetupForTest(t)
+	defer h2Server.Close()
+
 	transports := []func() io.ReadWriteCloser{
 		func() io.ReadWriteCloser { return NewMemoryBufferLen(1024) },
 		func() io.ReadWriteCloser {
@@ -251,6 +399,16 @@ func ReadWriteProtocolTest(t *testing.T, newFormat func(io.ReadWriteCloser) type
 			}
 			return http
 		},
+		func() io.ReadWriteCloser {
+			return newHTTP2ClientForTest(t, h2Server.URL, newHTTP2TransportForTest())
+		},
+		func() io.ReadWriteCloser { return webSocketTransportSetupForTest(t, WebSocketOptions{}) },
+	}
+	for _, level := range []CompressionLevel{DefaultCompression, BestSpeed, BestCompression, HuffmanOnly} {
+		level := level
+		transports = append(transports, func() io.ReadWriteCloser {
+			return NewCompressedTransport(NewMemoryBufferLen(1024), CompressOpts{Level: level})
+		})
 	}
 
 	doForAllTransports := func(protTest protocolTest) {
@@ -271,6 +429,7 @@ func ReadWriteProtocolTest(t *testing.T, newFormat func(io.ReadWriteCloser) type
 	doForAllTransports(ReadWriteFloat)
 	doForAllTransports(ReadWriteString)
 	doForAllTransports(ReadWriteBinary)
+	doForAllTransports(ReadWriteUUID)
 	doForAllTransports(ReadWriteStruct)
 
 	// perform set of many sequenced reads and writes
@@ -284,6 +443,20 @@ func ReadWriteProtocolTest(t *testing.T, newFormat func(io.ReadWriteCloser) type
 	})
 }
 
+// TestWebSocketTransportManyMessages runs WriteStruct/ReadStruct thousands
+// of times across a single WebSocket connection, with permessage-deflate
+// both on and off, to confirm the framing stays correct either way.
+func TestWebSocketTransportManyMessages(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		trans := webSocketTransportSetupForTest(t, WebSocketOptions{EnableCompression: compress})
+		defer trans.Close()
+		p := newBinaryFormat(trans)
+		for i := 0; i < 5000; i++ {
+			ReadWriteStruct(t, p, trans)
+		}
+	}
+}
+
 func ReadBool(t testing.TB, p types.Format, reader io.Reader) {
 	thetype := types.BOOL
 	thelen := len(boolValues)
@@ -723,6 +896,65 @@ func ReadWriteBinary(t testing.TB, p types.Format, readWriter io.ReadWriter) {
 	ReadBinary(t, p, readWriter)
 }
 
+func WriteUUID(t testing.TB, p types.Format, writer io.Writer) {
+	thetype := types.TYPE_UUID
+	thelen := len(UUID_VALUES)
+	err := p.WriteListBegin(thetype, thelen)
+	if err != nil {
+		t.Fatalf("%s: %T %T %q Error writing list begin: %q", "WriteUUID", p, writer, err, thetype)
+	}
+	for k, v := range UUID_VALUES {
+		err = p.WriteUUID(v)
+		if err != nil {
+			t.Fatalf("%s: %T %T %q Error writing uuid in list at index %d: %q", "WriteUUID", p, writer, err, k, v)
+		}
+	}
+	err = p.WriteListEnd()
+	if err != nil {
+		t.Fatalf("%s: %T %T %q Error writing list end: %q", "WriteUUID", p, writer, err, UUID_VALUES)
+	}
+	err = p.Flush()
+	if err != nil {
+		t.Fatalf("%s: %T %T %q Error flushing list of uuids: %q", "WriteUUID", p, writer, err, UUID_VALUES)
+	}
+}
+
+func ReadUUID(t testing.TB, p types.Format, reader io.Reader) {
+	thetype := types.TYPE_UUID
+	thelen := len(UUID_VALUES)
+	thetype2, thelen2, err := p.ReadListBegin()
+	if err != nil {
+		t.Fatalf("%s: %T %T %q Error reading list: %q", "ReadUUID", p, reader, err, UUID_VALUES)
+	}
+	_, ok := p.(*simpleJSONFormat)
+	if !ok {
+		if thetype != thetype2 {
+			t.Fatalf("%s: %T %T type %s != type %s", "ReadUUID", p, reader, thetype, thetype2)
+		}
+		if thelen != thelen2 {
+			t.Fatalf("%s: %T %T len %d != len %d", "ReadUUID", p, reader, thelen, thelen2)
+		}
+	}
+	for k, v := range UUID_VALUES {
+		value, err := p.ReadUUID()
+		if err != nil {
+			t.Fatalf("%s: %T %T %q Error reading uuid at index %d: %q", "ReadUUID", p, reader, err, k, v)
+		}
+		if v != value {
+			t.Fatalf("%s: %T %T %s != %s", "ReadUUID", p, reader, v, value)
+		}
+	}
+	err = p.ReadListEnd()
+	if err != nil {
+		t.Fatalf("%s: %T %T Unable to read list end: %q", "ReadUUID", p, reader, err)
+	}
+}
+
+func ReadWriteUUID(t testing.TB, p types.Format, readWriter io.ReadWriter) {
+	WriteUUID(t, p, readWriter)
+	ReadUUID(t, p, readWriter)
+}
+
 func WriteStruct(t testing.TB, p types.Format, writer io.Writer) {
 	v := structTestData
 	p.WriteStructBegin(v.name)
@@ -738,6 +970,12 @@ func WriteStruct(t testing.TB, p types.Format, writer io.Writer) {
 		t.Fatalf("%s: %T %T Unable to read string: %s", "WriteStruct", p, writer, err.Error())
 	}
 	p.WriteFieldEnd()
+	p.WriteFieldBegin(v.fields[2].name, v.fields[2].typ, v.fields[2].id)
+	err = p.WriteUUID(v.fields[2].value.(types.UUID))
+	if err != nil {
+		t.Fatalf("%s: %T %T Unable to write uuid: %s", "WriteStruct", p, writer, err.Error())
+	}
+	p.WriteFieldEnd()
 	p.WriteStructEnd()
 	err = p.Flush()
 	if err != nil {
@@ -799,15 +1037,243 @@ func ReadStruct(t testing.TB, p types.Format, reader io.Reader) {
 		t.Fatalf("%s: %T %T Unable to read field end: %s", "ReadStruct", p, reader, err.Error())
 	}
 
+	_, typeID, id, err = p.ReadFieldBegin()
+	if err != nil {
+		t.Fatalf("%s: %T %T Unable to read field begin: %s", "ReadStruct", p, reader, err.Error())
+	}
+	if v.fields[2].typ != typeID {
+		t.Fatalf("%s: %T %T type (%d) != (%d)", "ReadStruct", p, reader, v.fields[2].typ, typeID)
+	}
+	if v.fields[2].id != id {
+		t.Fatalf("%s: %T %T id (%d) != (%d)", "ReadStruct", p, reader, v.fields[2].id, id)
+	}
+
+	uuidVal, err := p.ReadUUID()
+	if err != nil {
+		t.Fatalf("%s: %T %T Unable to read uuid: %s", "ReadStruct", p, reader, err.Error())
+	}
+	if v.fields[2].value != uuidVal {
+		t.Fatalf("%s: %T %T value (%v) != (%v)", "ReadStruct", p, reader, v.fields[2].value, uuidVal)
+	}
+
+	err = p.ReadFieldEnd()
+	if err != nil {
+		t.Fatalf("%s: %T %T Unable to read field end: %s", "ReadStruct", p, reader, err.Error())
+	}
+
 	err = p.ReadStructEnd()
 	if err != nil {
 		t.Fatalf("%s: %T %T Unable to read struct end: %s", "ReadStruct", p, reader, err.Error())
 	}
 }
 
+// ReadWriteStruct wraps p in a format.DebugFormat so that, if the
+// round-trip fails partway through, the test output includes the exact
+// sequence of protocol calls that led to the divergence instead of just
+// the one failing assertion.
 func ReadWriteStruct(t testing.TB, p types.Format, readWriter io.ReadWriter) {
-	WriteStruct(t, p, readWriter)
-	ReadStruct(t, p, readWriter)
+	dbg := format.NewDebugWithBuffer(p, nil, "")
+	defer func() {
+		if t.Failed() {
+			t.Logf("%T call trace:\n%s", p, dumpDebugEvents(dbg.Dump()))
+		}
+	}()
+	WriteStruct(t, dbg, readWriter)
+	ReadStruct(t, dbg, readWriter)
+}
+
+func dumpDebugEvents(events []format.DebugEvent) string {
+	var sb strings.Builder
+	for _, e := range events {
+		sb.WriteString(e.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// writeFieldFilterTestStruct is WriteStruct's struct, but STOP-terminated,
+// since ReadStructFiltered (unlike ReadStruct) doesn't know the field
+// count ahead of time and must read fields until it sees one.
+func writeFieldFilterTestStruct(t testing.TB, p types.Format, writer io.Writer) {
+	v := structTestData
+	if err := p.WriteStructBegin(v.name); err != nil {
+		t.Fatalf("writeFieldFilterTestStruct: %T %T Unable to write struct begin: %s", p, writer, err)
+	}
+	for _, f := range v.fields {
+		if err := p.WriteFieldBegin(f.name, f.typ, f.id); err != nil {
+			t.Fatalf("writeFieldFilterTestStruct: %T %T Unable to write field begin: %s", p, writer, err)
+		}
+		var err error
+		switch f.typ {
+		case types.BOOL:
+			err = p.WriteBool(f.value.(bool))
+		case types.STRING:
+			err = p.WriteString(f.value.(string))
+		case types.TYPE_UUID:
+			err = p.WriteUUID(f.value.(types.UUID))
+		default:
+			t.Fatalf("writeFieldFilterTestStruct: unsupported field type %s", f.typ)
+		}
+		if err != nil {
+			t.Fatalf("writeFieldFilterTestStruct: %T %T Unable to write field value: %s", p, writer, err)
+		}
+		if err := p.WriteFieldEnd(); err != nil {
+			t.Fatalf("writeFieldFilterTestStruct: %T %T Unable to write field end: %s", p, writer, err)
+		}
+	}
+	if err := p.WriteFieldStop(); err != nil {
+		t.Fatalf("writeFieldFilterTestStruct: %T %T Unable to write field stop: %s", p, writer, err)
+	}
+	if err := p.WriteStructEnd(); err != nil {
+		t.Fatalf("writeFieldFilterTestStruct: %T %T Unable to write struct end: %s", p, writer, err)
+	}
+	if err := p.Flush(); err != nil {
+		t.Fatalf("writeFieldFilterTestStruct: %T %T Unable to flush: %s", p, writer, err)
+	}
+}
+
+// acceptedField is one field ReadStructFiltered materialized.
+type acceptedField struct {
+	id    int16
+	value interface{}
+}
+
+// ReadStructFiltered mirrors ReadStruct, but runs filter against every
+// field header instead of knowing the struct's shape ahead of time: fields
+// the filter accepts are decoded and returned, fields it rejects are
+// discarded via Format.Skip, and once it returns a stop decision every
+// remaining field (including the one that triggered it) is skipped
+// without consulting the program again.
+func ReadStructFiltered(t testing.TB, p types.Format, reader io.Reader, filter *FieldFilter) []acceptedField {
+	if _, err := p.ReadStructBegin(); err != nil {
+		t.Fatalf("ReadStructFiltered: %T %T Unable to read struct begin: %s", p, reader, err)
+	}
+
+	var accepted []acceptedField
+	stopped := false
+	for {
+		_, typeID, id, err := p.ReadFieldBegin()
+		if err != nil {
+			t.Fatalf("ReadStructFiltered: %T %T Unable to read field begin: %s", p, reader, err)
+		}
+		if typeID == types.STOP {
+			break
+		}
+
+		decision := decideSkip
+		if !stopped {
+			decision = filter.run(id, typeID)
+			if decision == decideStop {
+				stopped = true
+			}
+		}
+
+		if decision == decideAccept {
+			var value interface{}
+			var err error
+			switch typeID {
+			case types.BOOL:
+				value, err = p.ReadBool()
+			case types.STRING:
+				value, err = p.ReadString()
+			case types.TYPE_UUID:
+				value, err = p.ReadUUID()
+			default:
+				err = fmt.Errorf("ReadStructFiltered: unsupported field type %s", typeID)
+			}
+			if err != nil {
+				t.Fatalf("ReadStructFiltered: %T %T Unable to read accepted field %d: %s", p, reader, id, err)
+			}
+			accepted = append(accepted, acceptedField{id: id, value: value})
+		} else if err := p.Skip(typeID); err != nil {
+			t.Fatalf("ReadStructFiltered: %T %T Unable to skip field %d: %s", p, reader, id, err)
+		}
+
+		if err := p.ReadFieldEnd(); err != nil {
+			t.Fatalf("ReadStructFiltered: %T %T Unable to read field end: %s", p, reader, err)
+		}
+	}
+
+	if err := p.ReadStructEnd(); err != nil {
+		t.Fatalf("ReadStructFiltered: %T %T Unable to read struct end: %s", p, reader, err)
+	}
+	return accepted
+}
+
+// TestFieldFilter exercises CompileFieldFilter's verifier and runs a couple
+// of small programs over writeFieldFilterTestStruct's three fields (id 1
+// BOOL, id 2 STRING, id 3 UUID) through ReadStructFiltered.
+func TestFieldFilter(t *testing.T) {
+	t.Run("rejects backward branch", func(t *testing.T) {
+		_, err := CompileFieldFilter([]FieldFilterInsn{
+			{Op: OpLoadFieldID},
+			{Op: OpJEQ, Imm: 1, JT: 0},
+			{Op: OpAccept},
+		})
+		if err == nil {
+			t.Fatalf("CompileFieldFilter: expected an error for a backward jump target")
+		}
+	})
+
+	t.Run("rejects unreachable code", func(t *testing.T) {
+		_, err := CompileFieldFilter([]FieldFilterInsn{
+			{Op: OpLoadFieldID},
+			{Op: OpJEQ, Imm: 1, JT: 4},
+			{Op: OpAccept},
+			{Op: OpSkip},
+			{Op: OpAccept},
+		})
+		if err == nil {
+			t.Fatalf("CompileFieldFilter: expected an error for unreachable code")
+		}
+	})
+
+	t.Run("accept by id", func(t *testing.T) {
+		// Accept only field 2, skip everything else.
+		filter, err := CompileFieldFilter([]FieldFilterInsn{
+			{Op: OpLoadFieldID},        // 0
+			{Op: OpJEQ, Imm: 2, JT: 3}, // 1: id == 2 -> ACCEPT
+			{Op: OpSkip},               // 2
+			{Op: OpAccept},             // 3
+		})
+		if err != nil {
+			t.Fatalf("CompileFieldFilter: %s", err)
+		}
+
+		trans := NewMemoryBufferLen(1024)
+		p := newBinaryFormat(trans)
+		writeFieldFilterTestStruct(t, p, trans)
+		accepted := ReadStructFiltered(t, p, trans, filter)
+
+		if len(accepted) != 1 || accepted[0].id != 2 || accepted[0].value != "hi" {
+			t.Fatalf("ReadStructFiltered: got %+v, want exactly field 2 = %q", accepted, "hi")
+		}
+	})
+
+	t.Run("stop short-circuits the rest of the struct", func(t *testing.T) {
+		// Accept field 1; once a field id greater than 1 shows up, stop
+		// looking at the program at all and just skip the rest.
+		filter, err := CompileFieldFilter([]FieldFilterInsn{
+			{Op: OpLoadFieldID},        // 0
+			{Op: OpJEQ, Imm: 1, JT: 4}, // 1: id == 1 -> ACCEPT
+			{Op: OpJGT, Imm: 1, JT: 5}, // 2: id > 1  -> RET
+			{Op: OpSkip},               // 3
+			{Op: OpAccept},             // 4
+			{Op: OpRet},                // 5
+		})
+		if err != nil {
+			t.Fatalf("CompileFieldFilter: %s", err)
+		}
+
+		trans := NewMemoryBufferLen(1024)
+		p := newBinaryFormat(trans)
+		writeFieldFilterTestStruct(t, p, trans)
+		accepted := ReadStructFiltered(t, p, trans, filter)
+
+		if len(accepted) != 1 || accepted[0].id != 1 || accepted[0].value != true {
+			t.Fatalf("ReadStructFiltered: got %+v, want exactly field 1 = true", accepted)
+		}
+	})
 }
 
 func UnmatchedBeginEndProtocolTest(t *testing.T, formatFactory func(io.ReadWriter) types.Format) {
@@ -894,3 +1360,615 @@ func UnmatchedBeginEndProtocolTest(t *testing.T, formatFactory func(io.ReadWrite
 	})
 	trans.Close()
 }
+
+// assertStrictProtocolException fails t unless err is a *types.ProtocolException
+// of kind INVALID_DATA -- format.NewStrict's signature for every mismatch
+// StrictProtocolTest provokes below.
+func assertStrictProtocolException(t *testing.T, err error) {
+	t.Helper()
+	var pe *types.ProtocolException
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected a *types.ProtocolException, got %v (%T)", err, err)
+	}
+	if pe.Kind != types.INVALID_DATA {
+		t.Fatalf("expected ProtocolException kind INVALID_DATA, got %v", pe.Kind)
+	}
+}
+
+// StrictProtocolTest is UnmatchedBeginEndProtocolTest's opt-in sibling:
+// formatFactory's result is wrapped in format.NewStrict, so the same
+// unmatched End calls that test merely tolerates must instead return a
+// *types.ProtocolException here.
+func StrictProtocolTest(t *testing.T, formatFactory func(io.ReadWriter) types.Format) {
+	strictFactory := func(trans io.ReadWriter) types.Format {
+		return format.NewStrict(formatFactory(trans))
+	}
+	trans := NewMemoryBuffer()
+	t.Run("Read", func(t *testing.T) {
+		t.Run("Message", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.ReadMessageEnd())
+		})
+		t.Run("Struct", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.ReadStructEnd())
+		})
+		t.Run("Field", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.ReadFieldEnd())
+		})
+		t.Run("Map", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.ReadMapEnd())
+		})
+		t.Run("List", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.ReadListEnd())
+		})
+		t.Run("Set", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.ReadSetEnd())
+		})
+	})
+	t.Run("Write", func(t *testing.T) {
+		t.Run("Message", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.WriteMessageEnd())
+		})
+		t.Run("Struct", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.WriteStructEnd())
+		})
+		t.Run("Field", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.WriteFieldEnd())
+		})
+		t.Run("Map", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.WriteMapEnd())
+		})
+		t.Run("List", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.WriteListEnd())
+		})
+		t.Run("Set", func(t *testing.T) {
+			trans.Reset()
+			p := strictFactory(trans)
+			assertStrictProtocolException(t, p.WriteSetEnd())
+		})
+	})
+	trans.Close()
+}
+
+// genericField is one (id, type, value) entry of a conformance fixture's
+// STRUCT representation; nested structs/containers reuse the same JSON
+// shapes recursively (see decodeGeneric/encodeGeneric below).
+type genericField struct {
+	ID    int16           `json:"id"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type genericContainer struct {
+	ElemType string            `json:"elemType,omitempty"`
+	Values   []json.RawMessage `json:"values,omitempty"`
+	KeyType  string            `json:"keyType,omitempty"`
+	ValType  string            `json:"valueType,omitempty"`
+	Entries  []genericMapEntry `json:"entries,omitempty"`
+}
+
+type genericMapEntry struct {
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// conformanceFixture is one line of a testdata/conformance/*.jsonl corpus:
+// Hex is the canonical wire encoding for Type, and Value is that same
+// value in the JSON-friendly shape decodeGeneric/encodeGeneric use.
+type conformanceFixture struct {
+	Name  string          `json:"name"`
+	Type  string          `json:"type"`
+	Hex   string          `json:"hex"`
+	Value json.RawMessage `json:"value"`
+}
+
+var conformanceTypeByName = map[string]types.Type{
+	"BOOL": types.BOOL, "BYTE": types.BYTE, "DOUBLE": types.DOUBLE,
+	"I16": types.I16, "I32": types.I32, "I64": types.I64,
+	"STRING": types.STRING, "STRUCT": types.STRUCT, "MAP": types.MAP,
+	"SET": types.SET, "LIST": types.LIST, "FLOAT": types.FLOAT,
+	"UUID": types.TYPE_UUID,
+}
+
+func conformanceNonFiniteDouble(s string) (float64, bool) {
+	switch s {
+	case "NaN":
+		// The canonical quiet NaN (0x7ff8000000000000); math.NaN() also sets
+		// the lowest mantissa bit, which would not re-encode to the fixture.
+		return math.Float64frombits(0x7ff8000000000000), true
+	case "+Inf":
+		return math.Inf(1), true
+	case "-Inf":
+		return math.Inf(-1), true
+	default:
+		return 0, false
+	}
+}
+
+func conformanceDoubleToJSON(v float64) json.RawMessage {
+	if math.IsNaN(v) {
+		return json.RawMessage(`"NaN"`)
+	}
+	if math.IsInf(v, 1) {
+		return json.RawMessage(`"+Inf"`)
+	}
+	if math.IsInf(v, -1) {
+		return json.RawMessage(`"-Inf"`)
+	}
+	b, _ := json.Marshal(v)
+	return b
+}
+
+// decodeGeneric reads one value of typeID off p without knowing any
+// generated struct schema, producing the same JSON shape a conformance
+// fixture's "value" field uses.
+func decodeGeneric(p types.Format, typeID types.Type) (json.RawMessage, error) {
+	switch typeID {
+	case types.BOOL:
+		v, err := p.ReadBool()
+		if err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(v)
+		return b, nil
+	case types.BYTE:
+		v, err := p.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(v)
+		return b, nil
+	case types.I16:
+		v, err := p.ReadI16()
+		if err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(v)
+		return b, nil
+	case types.I32:
+		v, err := p.ReadI32()
+		if err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(v)
+		return b, nil
+	case types.I64:
+		v, err := p.ReadI64()
+		if err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(v)
+		return b, nil
+	case types.DOUBLE:
+		v, err := p.ReadDouble()
+		if err != nil {
+			return nil, err
+		}
+		return conformanceDoubleToJSON(v), nil
+	case types.FLOAT:
+		v, err := p.ReadFloat()
+		if err != nil {
+			return nil, err
+		}
+		return conformanceDoubleToJSON(float64(v)), nil
+	case types.STRING:
+		v, err := p.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(v)
+		return b, nil
+	case types.TYPE_UUID:
+		v, err := p.ReadUUID()
+		if err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(v.String())
+		return b, nil
+	case types.STRUCT:
+		if _, err := p.ReadStructBegin(); err != nil {
+			return nil, err
+		}
+		var fields []genericField
+		for {
+			_, ft, id, err := p.ReadFieldBegin()
+			if err != nil {
+				return nil, err
+			}
+			if ft == types.STOP {
+				break
+			}
+			value, err := decodeGeneric(p, ft)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, genericField{ID: id, Type: ft.String(), Value: value})
+			if err := p.ReadFieldEnd(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.ReadStructEnd(); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(fields)
+		return b, err
+	case types.MAP:
+		kt, vt, size, err := p.ReadMapBegin()
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]genericMapEntry, 0, size)
+		for i := 0; i < size; i++ {
+			k, err := decodeGeneric(p, kt)
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeGeneric(p, vt)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, genericMapEntry{Key: k, Value: v})
+		}
+		if err := p.ReadMapEnd(); err != nil {
+			return nil, err
+		}
+		// Not genericContainer: its omitempty would drop an empty "entries".
+		b, err := json.Marshal(map[string]interface{}{"keyType": kt.String(), "valueType": vt.String(), "entries": entries})
+		return b, err
+	case types.SET, types.LIST:
+		var et types.Type
+		var size int
+		var err error
+		if typeID == types.SET {
+			et, size, err = p.ReadSetBegin()
+		} else {
+			et, size, err = p.ReadListBegin()
+		}
+		if err != nil {
+			return nil, err
+		}
+		values := make([]json.RawMessage, 0, size)
+		for i := 0; i < size; i++ {
+			v, err := decodeGeneric(p, et)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		if typeID == types.SET {
+			err = p.ReadSetEnd()
+		} else {
+			err = p.ReadListEnd()
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Not genericContainer: its omitempty would drop an empty "values".
+		b, err := json.Marshal(map[string]interface{}{"elemType": et.String(), "values": values})
+		return b, err
+	default:
+		return nil, types.NewProtocolException(types.INVALID_DATA, "decodeGeneric: unsupported type "+typeID.String())
+	}
+}
+
+// encodeGeneric is decodeGeneric's inverse: it writes a JSON value in the
+// conformance fixture shape back out through p as typeID.
+func encodeGeneric(p types.Format, typeID types.Type, raw json.RawMessage) error {
+	switch typeID {
+	case types.BOOL:
+		var v bool
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return p.WriteBool(v)
+	case types.BYTE:
+		var v byte
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return p.WriteByte(v)
+	case types.I16:
+		var v int16
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return p.WriteI16(v)
+	case types.I32:
+		var v int32
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return p.WriteI32(v)
+	case types.I64:
+		var v int64
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return p.WriteI64(v)
+	case types.DOUBLE, types.FLOAT:
+		var s string
+		var v float64
+		if err := json.Unmarshal(raw, &s); err == nil {
+			nf, ok := conformanceNonFiniteDouble(s)
+			if !ok {
+				return fmt.Errorf("encodeGeneric: unrecognized non-finite double %q", s)
+			}
+			v = nf
+		} else if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if typeID == types.FLOAT {
+			return p.WriteFloat(float32(v))
+		}
+		return p.WriteDouble(v)
+	case types.STRING:
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		return p.WriteString(v)
+	case types.TYPE_UUID:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		u, err := types.ParseUUID(s)
+		if err != nil {
+			return err
+		}
+		return p.WriteUUID(u)
+	case types.STRUCT:
+		var fields []genericField
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return err
+		}
+		if err := p.WriteStructBegin(""); err != nil {
+			return err
+		}
+		for _, f := range fields {
+			ft, ok := conformanceTypeByName[f.Type]
+			if !ok {
+				return fmt.Errorf("encodeGeneric: unknown field type %q", f.Type)
+			}
+			if err := p.WriteFieldBegin("", ft, f.ID); err != nil {
+				return err
+			}
+			if err := encodeGeneric(p, ft, f.Value); err != nil {
+				return err
+			}
+			if err := p.WriteFieldEnd(); err != nil {
+				return err
+			}
+		}
+		return p.WriteFieldStop()
+	case types.MAP:
+		var c genericContainer
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return err
+		}
+		kt, vt := conformanceTypeByName[c.KeyType], conformanceTypeByName[c.ValType]
+		if err := p.WriteMapBegin(kt, vt, len(c.Entries)); err != nil {
+			return err
+		}
+		for _, e := range c.Entries {
+			if err := encodeGeneric(p, kt, e.Key); err != nil {
+				return err
+			}
+			if err := encodeGeneric(p, vt, e.Value); err != nil {
+				return err
+			}
+		}
+		return p.WriteMapEnd()
+	case types.SET, types.LIST:
+		var c genericContainer
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return err
+		}
+		et, ok := conformanceTypeByName[c.ElemType]
+		if !ok {
+			return fmt.Errorf("encodeGeneric: unknown elem type %q", c.ElemType)
+		}
+		var err error
+		if typeID == types.SET {
+			err = p.WriteSetBegin(et, len(c.Values))
+		} else {
+			err = p.WriteListBegin(et, len(c.Values))
+		}
+		if err != nil {
+			return err
+		}
+		for _, v := range c.Values {
+			if err := encodeGeneric(p, et, v); err != nil {
+				return err
+			}
+		}
+		if typeID == types.SET {
+			return p.WriteSetEnd()
+		}
+		return p.WriteListEnd()
+	default:
+		return types.NewProtocolException(types.INVALID_DATA, "encodeGeneric: unsupported type "+typeID.String())
+	}
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// RunBinarySelfConformanceSuite loads every testdata/conformance/*.jsonl
+// fixture and, for each, asserts that formatFactory's format decodes the
+// fixture's wire bytes into the expected generic value and re-encodes
+// that value back to the exact same bytes. That catches this binding's
+// own wire format silently drifting; as the name says, it is a
+// self-conformance check, not a cross-implementation one -- it does not
+// prove bit-compatibility with the C++, Python, or any other Thrift
+// implementation.
+//
+// Fixtures are hand-authored against this package's documented binary
+// protocol wire format (see binary_format.go), not generated from a
+// reference implementation -- this tree has no compact/JSON Format
+// implementations and no C++/Python Thrift toolchain available to
+// generate fixtures from. A real cross-implementation corpus (fixtures
+// produced by those reference implementations, checked in and compared
+// byte-for-byte) remains future work.
+func RunBinarySelfConformanceSuite(t *testing.T, formatFactory func(io.ReadWriter) types.Format) {
+	dir := "testdata/conformance"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("RunBinarySelfConformanceSuite: unable to read %s: %s", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".jsonl" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("RunBinarySelfConformanceSuite: unable to read %s: %s", path, err)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if line == "" {
+				continue
+			}
+			var fx conformanceFixture
+			if err := json.Unmarshal([]byte(line), &fx); err != nil {
+				t.Fatalf("RunBinarySelfConformanceSuite: %s: malformed fixture: %s", path, err)
+			}
+			t.Run(fx.Name, func(t *testing.T) {
+				typeID, ok := conformanceTypeByName[fx.Type]
+				if !ok {
+					t.Fatalf("RunBinarySelfConformanceSuite: %s: unknown type %q", fx.Name, fx.Type)
+				}
+				wire, err := hex.DecodeString(fx.Hex)
+				if err != nil {
+					t.Fatalf("RunBinarySelfConformanceSuite: %s: malformed hex: %s", fx.Name, err)
+				}
+
+				decoded, err := decodeGeneric(formatFactory(bytes.NewBuffer(wire)), typeID)
+				if err != nil {
+					t.Fatalf("RunBinarySelfConformanceSuite: %s: decode: %s", fx.Name, err)
+				}
+				if !jsonEqual(decoded, fx.Value) {
+					t.Fatalf("RunBinarySelfConformanceSuite: %s: decoded value %s != expected %s", fx.Name, decoded, fx.Value)
+				}
+
+				var out bytes.Buffer
+				reencodeFormat := formatFactory(&out)
+				if err := encodeGeneric(reencodeFormat, typeID, fx.Value); err != nil {
+					t.Fatalf("RunBinarySelfConformanceSuite: %s: encode: %s", fx.Name, err)
+				}
+				if err := reencodeFormat.Flush(); err != nil {
+					t.Fatalf("RunBinarySelfConformanceSuite: %s: flush: %s", fx.Name, err)
+				}
+				if got := hex.EncodeToString(out.Bytes()); got != fx.Hex {
+					t.Fatalf("RunBinarySelfConformanceSuite: %s: re-encoded bytes %s != expected %s", fx.Name, got, fx.Hex)
+				}
+			})
+		}
+	}
+}
+
+func TestBinaryConformance(t *testing.T) {
+	RunBinarySelfConformanceSuite(t, newBinaryFormat)
+}
+
+// loadConformanceFixtureBytes reads a conformance fixture file's wire bytes
+// back out, for use as fuzz corpus seeds -- known-good encodings make far
+// better starting points for the mutator than an empty corpus.
+func loadConformanceFixtureBytes(f *testing.F, path string) [][]byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		f.Fatalf("loadConformanceFixtureBytes: %s: %s", path, err)
+	}
+	var out [][]byte
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var fx conformanceFixture
+		if err := json.Unmarshal([]byte(line), &fx); err != nil {
+			f.Fatalf("loadConformanceFixtureBytes: %s: %s", path, err)
+		}
+		wire, err := hex.DecodeString(fx.Hex)
+		if err != nil {
+			f.Fatalf("loadConformanceFixtureBytes: %s: %s", path, err)
+		}
+		out = append(out, wire)
+	}
+	return out
+}
+
+// FuzzBinaryDecode throws arbitrary bytes at binaryFormat's Skip and
+// types.DecodeValue, both entered at STRUCT since that's the top-level
+// shape every Thrift message body takes. Neither is expected to succeed
+// on most mutated input, and their errors are discarded: the only thing
+// checked is that malformed input makes them return (bounded by
+// SkipOptions) rather than panic or run away with unbounded
+// recursion/allocation.
+func FuzzBinaryDecode(f *testing.F) {
+	for _, seed := range loadConformanceFixtureBytes(f, "testdata/conformance/binary.jsonl") {
+		f.Add(seed)
+	}
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		skipFormat := newBinaryFormat(bytes.NewBuffer(data))
+		skipFormat.SetSkipOptions(types.DefaultSkipOptions)
+		_ = skipFormat.Skip(types.STRUCT)
+
+		valueFormat := newBinaryFormat(bytes.NewBuffer(data))
+		_, _ = types.DecodeValue(valueFormat, types.STRUCT, types.DefaultSkipOptions)
+	})
+}
+
+// FuzzCompactDecode and FuzzJSONDecode are placeholders: this tree has no
+// compact or JSON Format implementation to fuzz, only binaryFormat. Once
+// one of those lands, give it the same Skip + types.DecodeValue treatment
+// FuzzBinaryDecode does above.
+func FuzzCompactDecode(f *testing.F) {
+	f.Skip("no compact Format implementation in this tree yet")
+}
+
+func FuzzJSONDecode(f *testing.F) {
+	f.Skip("no JSON Format implementation in this tree yet")
+}
+
+// FuzzHeaderDecode fuzzes the THeader frame parser: data is fed in as a
+// full frame (length prefix included), exercising both headerTransport's
+// own framing/header parsing and the wrapped binaryFormat's Skip on
+// whatever's left once a frame is successfully delimited.
+func FuzzHeaderDecode(f *testing.F) {
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		buf := NewMemoryBufferLen(len(data))
+		buf.Write(data)
+		trans := newHeaderTransport(buf)
+		p := NewHeaderProtocol(trans, newBinaryFormat(trans))
+		p.SetSkipOptions(types.DefaultSkipOptions)
+		_ = p.Skip(types.STRUCT)
+	})
+}
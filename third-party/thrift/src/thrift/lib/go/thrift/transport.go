@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"errors"
+	"io"
+)
+
+// Transport is the base interface implemented by every Thrift transport in
+// this package (memory buffers, framed/header transports, the HTTP client,
+// ...). It is a ReadWriteCloser plus an explicit Flush, since several
+// transports buffer writes until Flush is called.
+type Transport interface {
+	io.ReadWriteCloser
+	Flush() error
+}
+
+// TransportExceptionType enumerates the kinds of failure a Transport can
+// report. It deliberately mirrors the values used by the other Thrift
+// language bindings so errors are recognizable across implementations.
+type TransportExceptionType int
+
+const (
+	UNKNOWN_TRANSPORT_EXCEPTION TransportExceptionType = 0
+	NOT_OPEN                    TransportExceptionType = 1
+	ALREADY_OPEN                TransportExceptionType = 2
+	TIMED_OUT                   TransportExceptionType = 3
+	END_OF_FILE                 TransportExceptionType = 4
+	NETWORK_ERROR               TransportExceptionType = 5
+	SIZE_LIMIT                  TransportExceptionType = 6
+)
+
+// DefaultMaxFrameSize is the MaxFrameSize framedTransport and
+// headerTransport use when none is configured: large enough for any
+// reasonable Thrift message, small enough that a peer advertising a lying
+// frame length (e.g. 0x7FFFFFFF) can't force a multi-gigabyte allocation.
+const DefaultMaxFrameSize int32 = 16 * 1024 * 1024
+
+// TransportException is returned by Transport implementations for any
+// failure that isn't a plain io.EOF; it carries a TransportExceptionType so
+// callers can distinguish, e.g., a closed connection from a timeout.
+type TransportException struct {
+	typ TransportExceptionType
+	err error
+}
+
+func (e *TransportException) TypeID() TransportExceptionType { return e.typ }
+func (e *TransportException) Unwrap() error                  { return e.err }
+
+func (e *TransportException) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return "unknown transport exception"
+}
+
+// NewTransportException creates a TransportException of the given type with
+// a message.
+func NewTransportException(typ TransportExceptionType, msg string) *TransportException {
+	return &TransportException{typ: typ, err: errors.New(msg)}
+}
+
+// NewTransportExceptionFromError wraps err as a TransportException, reusing
+// its type if err already is one (or wraps one) rather than flattening it
+// to UNKNOWN_TRANSPORT_EXCEPTION.
+func NewTransportExceptionFromError(err error) *TransportException {
+	if err == nil {
+		return nil
+	}
+	var te *TransportException
+	if errors.As(err, &te) {
+		return te
+	}
+	if isEOF(err) {
+		return &TransportException{typ: END_OF_FILE, err: err}
+	}
+	return &TransportException{typ: UNKNOWN_TRANSPORT_EXCEPTION, err: err}
+}
+
+// isEOF reports whether err is, or wraps, io.EOF or a TransportException of
+// type END_OF_FILE.
+func isEOF(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	var te *TransportException
+	if errors.As(err, &te) {
+		return te.typ == END_OF_FILE
+	}
+	return false
+}
+
+// flush calls Flush on w if it implements one, otherwise it is a no-op.
+// This lets shared test helpers operate on plain io.Writers as well as
+// full Transports.
+func flush(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -17,9 +17,18 @@
 package thrift
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"sync"
 	"testing"
+
+	"golang.org/x/net/http2"
 )
 
 const TRANSPORT_BINARY_DATA_SIZE = 4096
@@ -147,6 +156,253 @@ func transportHTTPClientTest(t *testing.T, writer io.Writer, reader io.Reader) {
 	}
 }
 
+// headerTransportTransformsTest round-trips transport_bdata through a
+// headerTransport once per registered transform (plus TransformNone and the
+// built-in TransformZlib), asserting the data survives the trip and that
+// SetTransform returns an error for an unregistered id.
+func headerTransportTransformsTest(t *testing.T, newTrans func() *headerTransport) {
+	ids := []uint32{TransformNone, TransformZlib, TransformZstd, TransformSnappy, TransformLZ4}
+	for _, id := range ids {
+		trans := newTrans()
+		if err := trans.SetTransform(id); err != nil {
+			t.Fatalf("SetTransform(%#x) returned error: %s", id, err)
+		}
+		if _, err := trans.Write(transport_bdata); err != nil {
+			t.Fatalf("transform %#x: cannot write binary data: %s", id, err)
+		}
+		if err := flush(trans); err != nil {
+			t.Fatalf("transform %#x: cannot flush: %s", id, err)
+		}
+		if err := trans.ResetProtocol(); err != nil {
+			t.Fatalf("transform %#x: cannot read frame: %s", id, err)
+		}
+		buf := make([]byte, TRANSPORT_BINARY_DATA_SIZE)
+		if _, err := io.ReadFull(trans, buf); err != nil {
+			t.Fatalf("transform %#x: cannot read binary data: %s", id, err)
+		}
+		for k, v := range buf {
+			if v != transport_bdata[k] {
+				t.Fatalf("transform %#x: read %d instead of %d for index %d", id, v, transport_bdata[k], k)
+			}
+		}
+	}
+
+	unknown := newTrans()
+	if err := unknown.SetTransform(0x7f); err == nil {
+		t.Fatalf("SetTransform with an unregistered id should have returned an error")
+	}
+}
+
+// TestHTTPClientBufferPool hammers many short-lived httpClients
+// concurrently and asserts that no client still holds a pooled request
+// buffer after Flush or after Close. It does not check that the pool hands
+// a released buffer back out to a later client.
+func TestHTTPClientBufferPool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	const clients = 64
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := newHTTPPostClient(server.URL)
+			if err != nil {
+				t.Errorf("newHTTPPostClient: %s", err)
+				return
+			}
+			if _, err := c.Write(transport_bdata); err != nil {
+				t.Errorf("Write: %s", err)
+				return
+			}
+			if err := c.Flush(); err != nil {
+				t.Errorf("Flush: %s", err)
+				return
+			}
+			if c.requestBuffer != nil {
+				t.Errorf("httpClient held onto its request buffer past Flush")
+			}
+			if err := c.Close(); err != nil {
+				t.Errorf("Close: %s", err)
+			}
+			if c.requestBuffer != nil {
+				t.Errorf("httpClient held onto its request buffer past Close")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// transportHTTPClientCookieTest verifies that a Set-Cookie header from a
+// mock server is stored in the client's CookieJar and echoed back on the
+// subsequent request.
+func transportHTTPClientCookieTest(t *testing.T) {
+	var sawCookieOnSecondRequest bool
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+		} else {
+			if cookie, err := r.Cookie("session"); err == nil && cookie.Value == "abc123" {
+				sawCookieOnSecondRequest = true
+			}
+		}
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	c, err := newHTTPPostClient(server.URL)
+	if err != nil {
+		t.Fatalf("newHTTPPostClient: %s", err)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookiejar.New: %s", err)
+	}
+	c.SetCookieJar(jar)
+
+	c.Write(transport_bdata)
+	if err := c.Flush(); err != nil {
+		t.Fatalf("first Flush: %s", err)
+	}
+
+	c.Write(transport_bdata)
+	if err := c.Flush(); err != nil {
+		t.Fatalf("second Flush: %s", err)
+	}
+
+	if !sawCookieOnSecondRequest {
+		t.Errorf("server did not see the session cookie on the second request")
+	}
+	if cookies := c.Cookies(); len(cookies) != 1 || cookies[0].Value != "abc123" {
+		t.Errorf("Cookies() = %v, want one cookie with value abc123", cookies)
+	}
+}
+
+// TestHTTPClientForceHTTP2 stands up an httptest TLS server advertising h2
+// via ALPN and asserts a ForceHTTP2 client actually negotiates it.
+func TestHTTPClientForceHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("server saw ProtoMajor = %d, want 2", r.ProtoMajor)
+		}
+		io.Copy(w, r.Body)
+	}))
+	server.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	server.StartTLS()
+	defer server.Close()
+
+	c, err := NewHTTPClientWithOptions(server.URL, HTTPClientOptions{ForceHTTP2: true})
+	if err != nil {
+		t.Fatalf("NewHTTPClientWithOptions: %s", err)
+	}
+	c.client.Transport.(*http2.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+
+	c.Write(transport_bdata)
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush: %s", err)
+	}
+	if c.response.ProtoMajor != 2 {
+		t.Errorf("response ProtoMajor = %d, want 2", c.response.ProtoMajor)
+	}
+}
+
+// TestHeaderTransportPersistentHeader verifies that a persistent header is
+// observed by the server on every subsequent frame, while a normal header
+// is only observed on the frame it was set for.
+func TestHeaderTransportPersistentHeader(t *testing.T) {
+	client, server := tcpStreamHeaderPair(t)
+	defer client.Close()
+	defer server.Close()
+
+	client.SetPersistentHeader("persistent-key", "persistent-value")
+	client.SetHeader("normal-key", "normal-value")
+	client.Write(transport_bdata)
+	if err := client.Flush(); err != nil {
+		t.Fatalf("first Flush: %s", err)
+	}
+
+	client.Write(transport_bdata)
+	if err := client.Flush(); err != nil {
+		t.Fatalf("second Flush: %s", err)
+	}
+
+	for frame := 1; frame <= 2; frame++ {
+		if err := server.ResetProtocol(); err != nil {
+			t.Fatalf("frame %d: ResetProtocol: %s", frame, err)
+		}
+		if v, ok := server.Header("persistent-key"); !ok || v != "persistent-value" {
+			t.Errorf("frame %d: persistent-key = %q, %v; want persistent-value, true", frame, v, ok)
+		}
+		_, hasNormal := server.Header("normal-key")
+		if frame == 1 && !hasNormal {
+			t.Errorf("frame 1: expected normal-key to be present")
+		}
+		if frame == 2 && hasNormal {
+			t.Errorf("frame 2: normal-key should not have been resent")
+		}
+	}
+}
+
+// tcpStreamHeaderPair sets up a pair of headerTransports over a real TCP
+// connection, mirroring tcpStreamSetupForTest in protocol_test.go.
+func tcpStreamHeaderPair(t *testing.T) (client, server *headerTransport) {
+	r, w := tcpStreamSetupForTest(t)
+	return newHeaderTransport(w.(io.ReadWriteCloser)), newHeaderTransport(r.(io.ReadWriteCloser))
+}
+
+// TransportSizeLimitTest feeds a crafted, oversized frame length to a
+// framed or header transport and asserts the read fails with a
+// TransportException of type SIZE_LIMIT rather than attempting to
+// allocate or read a frame of that size.
+func TransportSizeLimitTest(t *testing.T, newReader func(underlying io.Reader, maxFrameSize int32) io.Reader) {
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], 0x7FFFFFFF)
+	underlying := bytes.NewReader(hdr[:])
+
+	reader := newReader(underlying, 4096)
+	buf := make([]byte, 1)
+	_, err := reader.Read(buf)
+	if err == nil {
+		t.Fatalf("expected an error reading an oversized frame, got nil")
+	}
+	te, ok := err.(*TransportException)
+	if !ok {
+		t.Fatalf("expected *TransportException, got %T: %v", err, err)
+	}
+	if te.TypeID() != SIZE_LIMIT {
+		t.Fatalf("expected SIZE_LIMIT, got %v", te.TypeID())
+	}
+}
+
+func TestFramedTransportSizeLimit(t *testing.T) {
+	TransportSizeLimitTest(t, func(underlying io.Reader, maxFrameSize int32) io.Reader {
+		trans := newFramedTransport(struct {
+			io.Reader
+			io.Writer
+			io.Closer
+		}{Reader: underlying})
+		trans.MaxFrameSize = maxFrameSize
+		return trans
+	})
+}
+
+func TestHeaderTransportSizeLimit(t *testing.T) {
+	TransportSizeLimitTest(t, func(underlying io.Reader, maxFrameSize int32) io.Reader {
+		trans := newHeaderTransport(struct {
+			io.Reader
+			io.Writer
+			io.Closer
+		}{Reader: underlying})
+		trans.MaxFrameSize = maxFrameSize
+		return trans
+	})
+}
+
 func TestIsEOF(t *testing.T) {
 	if !isEOF(io.EOF) {
 		t.Fatalf("expected true")
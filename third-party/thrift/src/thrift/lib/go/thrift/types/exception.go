@@ -0,0 +1,48 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+// ProtocolExceptionType enumerates the kinds of failure a Format can report
+// while decoding a malformed or unexpected wire stream.
+type ProtocolExceptionType int
+
+const (
+	UNKNOWN_PROTOCOL_EXCEPTION ProtocolExceptionType = 0
+	INVALID_DATA               ProtocolExceptionType = 1
+	NEGATIVE_SIZE              ProtocolExceptionType = 2
+	SIZE_LIMIT                 ProtocolExceptionType = 3
+	BAD_VERSION                ProtocolExceptionType = 4
+	NOT_IMPLEMENTED            ProtocolExceptionType = 5
+	DEPTH_LIMIT_EXCEEDED       ProtocolExceptionType = 6
+)
+
+// ProtocolException is returned by Format implementations for any failure
+// that is about the shape of the data rather than the underlying
+// transport (use thrift.TransportException for that).
+type ProtocolException struct {
+	Kind ProtocolExceptionType
+	Msg  string
+}
+
+func (e *ProtocolException) Error() string {
+	return e.Msg
+}
+
+// NewProtocolException creates a ProtocolException of the given kind.
+func NewProtocolException(kind ProtocolExceptionType, msg string) *ProtocolException {
+	return &ProtocolException{Kind: kind, Msg: msg}
+}
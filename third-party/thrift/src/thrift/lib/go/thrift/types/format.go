@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+// Format is implemented by each concrete wire protocol (binary, compact,
+// JSON, simple-JSON, ...). It reads and writes the primitive Thrift types
+// plus the structural Begin/End markers that bracket messages, structs,
+// fields, and containers.
+type Format interface {
+	WriteMessageBegin(name string, typeID byte, seqID int32) error
+	WriteMessageEnd() error
+	WriteStructBegin(name string) error
+	WriteStructEnd() error
+	WriteFieldBegin(name string, typeID Type, id int16) error
+	WriteFieldEnd() error
+	WriteFieldStop() error
+	WriteMapBegin(keyType, valueType Type, size int) error
+	WriteMapEnd() error
+	WriteListBegin(elemType Type, size int) error
+	WriteListEnd() error
+	WriteSetBegin(elemType Type, size int) error
+	WriteSetEnd() error
+	WriteBool(value bool) error
+	WriteByte(value byte) error
+	WriteI16(value int16) error
+	WriteI32(value int32) error
+	WriteI64(value int64) error
+	WriteDouble(value float64) error
+	WriteFloat(value float32) error
+	WriteString(value string) error
+	WriteBinary(value []byte) error
+	WriteUUID(value UUID) error
+
+	ReadMessageBegin() (name string, typeID byte, seqID int32, err error)
+	ReadMessageEnd() error
+	ReadStructBegin() (name string, err error)
+	ReadStructEnd() error
+	ReadFieldBegin() (name string, typeID Type, id int16, err error)
+	ReadFieldEnd() error
+	ReadMapBegin() (keyType, valueType Type, size int, err error)
+	ReadMapEnd() error
+	ReadListBegin() (elemType Type, size int, err error)
+	ReadListEnd() error
+	ReadSetBegin() (elemType Type, size int, err error)
+	ReadSetEnd() error
+	ReadBool() (bool, error)
+	ReadByte() (byte, error)
+	ReadI16() (int16, error)
+	ReadI32() (int32, error)
+	ReadI64() (int64, error)
+	ReadDouble() (float64, error)
+	ReadFloat() (float32, error)
+	ReadString() (string, error)
+	ReadBinary() ([]byte, error)
+	ReadUUID() (UUID, error)
+
+	// Skip reads and discards a single value of typeID, recursing into
+	// containers and structs as needed, subject to the bounds set by
+	// SetSkipOptions.
+	Skip(typeID Type) error
+	// SetSkipOptions overrides the bounds Skip enforces against malformed
+	// or adversarial input. Implementations apply DefaultSkipOptions until
+	// this is called.
+	SetSkipOptions(opts SkipOptions)
+	Flush() error
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+// SkipOptions bounds how much work Format.Skip will do against malformed
+// or adversarial input: a stream advertising deeply nested containers or a
+// multi-billion-element size would otherwise recurse or iterate until it
+// exhausts memory or CPU before any caller gets a chance to reject it.
+//
+// MaxDepth bounds container/struct nesting, MaxContainerSize bounds any
+// single map/set/list size declaration, and MaxBytes bounds the total size
+// of the values a single top-level Skip call reads: primitive widths and
+// string/binary contents. Field headers, container headers and length
+// prefixes are not counted against it.
+type SkipOptions struct {
+	MaxDepth         int
+	MaxContainerSize int
+	MaxBytes         int64
+}
+
+// DefaultSkipOptions is what every Format implementation applies until
+// overridden with SetSkipOptions.
+var DefaultSkipOptions = SkipOptions{
+	MaxDepth:         64,
+	MaxContainerSize: 1_000_000,
+	MaxBytes:         64 * 1024 * 1024,
+}
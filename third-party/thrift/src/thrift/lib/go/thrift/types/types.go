@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package types holds the wire-format-independent pieces shared by every
+// Thrift protocol implementation in this module: the Type enum and the
+// Format interface each encoder/decoder (binary, compact, JSON, ...)
+// implements.
+package types
+
+import "fmt"
+
+// Type is the Thrift field/element wire type, using the same numeric
+// values as the other language bindings so a byte read off the wire can be
+// compared directly against these constants.
+type Type byte
+
+const (
+	STOP      Type = 0
+	VOID      Type = 1
+	BOOL      Type = 2
+	BYTE      Type = 3
+	DOUBLE    Type = 4
+	I16       Type = 6
+	I32       Type = 8
+	I64       Type = 10
+	STRING    Type = 11
+	STRUCT    Type = 12
+	MAP       Type = 13
+	SET       Type = 14
+	LIST      Type = 15
+	UTF8      Type = 16
+	UTF16     Type = 17
+	FLOAT     Type = 19
+	TYPE_UUID Type = 20
+)
+
+func (t Type) String() string {
+	switch t {
+	case STOP:
+		return "STOP"
+	case VOID:
+		return "VOID"
+	case BOOL:
+		return "BOOL"
+	case BYTE:
+		return "BYTE"
+	case DOUBLE:
+		return "DOUBLE"
+	case I16:
+		return "I16"
+	case I32:
+		return "I32"
+	case I64:
+		return "I64"
+	case STRING:
+		return "STRING"
+	case STRUCT:
+		return "STRUCT"
+	case MAP:
+		return "MAP"
+	case SET:
+		return "SET"
+	case LIST:
+		return "LIST"
+	case UTF8:
+		return "UTF8"
+	case UTF16:
+		return "UTF16"
+	case FLOAT:
+		return "FLOAT"
+	case TYPE_UUID:
+		return "UUID"
+	default:
+		return fmt.Sprintf("Type(%d)", byte(t))
+	}
+}
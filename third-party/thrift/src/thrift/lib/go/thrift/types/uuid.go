@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// UUID is Thrift's native uuid type: 16 bytes on the wire for binary and
+// compact, the canonical xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx hex string
+// for JSON and simple-JSON.
+type UUID [16]byte
+
+// ParseUUID parses the canonical 8-4-4-4-12 hex-and-dashes form.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("types: %q is not a canonical UUID string", s)
+	}
+	hexPart := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	b, err := hex.DecodeString(hexPart)
+	if err != nil {
+		return u, fmt.Errorf("types: %q is not a canonical UUID string: %w", s, err)
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+// MustParseUUID is ParseUUID, panicking on a malformed string. Intended for
+// constant UUIDs known at compile time.
+func MustParseUUID(s string) UUID {
+	u, err := ParseUUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// String returns the canonical 8-4-4-4-12 hex-and-dashes form.
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:36], u[10:16])
+	return string(buf[:])
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import "fmt"
+
+// Value is a generic, reflection-free decoded Thrift value: a tagged union
+// over the primitive and container shapes Type describes. DecodeValue
+// walks a Format into this shape without needing a generated struct
+// schema -- chiefly useful for fuzzing and other tooling that only has
+// raw bytes and a starting Type to work from.
+type Value struct {
+	Type    Type
+	Bool    bool
+	Byte    byte
+	I16     int16
+	I32     int32
+	I64     int64
+	Double  float64
+	Float   float32
+	String  string
+	Binary  []byte
+	UUID    UUID
+	Fields  []Field    // STRUCT
+	Elems   []Value    // SET, LIST
+	Entries []MapEntry // MAP
+}
+
+// Field is one (id, value) pair of a decoded STRUCT Value.
+type Field struct {
+	ID    int16
+	Value Value
+}
+
+// MapEntry is one (key, value) pair of a decoded MAP Value.
+type MapEntry struct {
+	Key   Value
+	Value Value
+}
+
+// DecodeValue reads one value of typeID off f into a Value tree, applying
+// opts the same way Format.Skip does: it returns a ProtocolException
+// instead of recursing or preallocating without bound when the input
+// advertises nesting past MaxDepth or a container size past
+// MaxContainerSize.
+func DecodeValue(f Format, typeID Type, opts SkipOptions) (Value, error) {
+	budget := opts.MaxBytes
+	return decodeValue(f, typeID, opts, 0, &budget)
+}
+
+func decodeValue(f Format, typeID Type, opts SkipOptions, depth int, byteBudget *int64) (Value, error) {
+	if depth > opts.MaxDepth {
+		return Value{}, NewProtocolException(DEPTH_LIMIT_EXCEEDED, "DecodeValue exceeded MaxDepth")
+	}
+	charge := func(n int64, err error) error {
+		if err != nil {
+			return err
+		}
+		*byteBudget -= n
+		if *byteBudget < 0 {
+			return NewProtocolException(SIZE_LIMIT, "DecodeValue exceeded MaxBytes")
+		}
+		return nil
+	}
+	switch typeID {
+	case BOOL:
+		v, err := f.ReadBool()
+		if err := charge(1, err); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: BOOL, Bool: v}, nil
+	case BYTE:
+		v, err := f.ReadByte()
+		if err := charge(1, err); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: BYTE, Byte: v}, nil
+	case I16:
+		v, err := f.ReadI16()
+		if err := charge(2, err); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: I16, I16: v}, nil
+	case I32:
+		v, err := f.ReadI32()
+		if err := charge(4, err); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: I32, I32: v}, nil
+	case I64:
+		v, err := f.ReadI64()
+		if err := charge(8, err); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: I64, I64: v}, nil
+	case DOUBLE:
+		v, err := f.ReadDouble()
+		if err := charge(8, err); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: DOUBLE, Double: v}, nil
+	case FLOAT:
+		v, err := f.ReadFloat()
+		if err := charge(4, err); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: FLOAT, Float: v}, nil
+	case STRING:
+		v, err := f.ReadBinary()
+		if err := charge(int64(len(v)), err); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: STRING, String: string(v), Binary: v}, nil
+	case TYPE_UUID:
+		v, err := f.ReadUUID()
+		if err := charge(16, err); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: TYPE_UUID, UUID: v}, nil
+	case STRUCT:
+		if _, err := f.ReadStructBegin(); err != nil {
+			return Value{}, err
+		}
+		var fields []Field
+		for {
+			_, ft, id, err := f.ReadFieldBegin()
+			if err != nil {
+				return Value{}, err
+			}
+			if ft == STOP {
+				break
+			}
+			fv, err := decodeValue(f, ft, opts, depth+1, byteBudget)
+			if err != nil {
+				return Value{}, err
+			}
+			fields = append(fields, Field{ID: id, Value: fv})
+			if err := f.ReadFieldEnd(); err != nil {
+				return Value{}, err
+			}
+		}
+		if err := f.ReadStructEnd(); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: STRUCT, Fields: fields}, nil
+	case MAP:
+		kt, vt, size, err := f.ReadMapBegin()
+		if err != nil {
+			return Value{}, err
+		}
+		if err := checkDecodedContainerSize(size, opts); err != nil {
+			return Value{}, err
+		}
+		entries := make([]MapEntry, 0, size)
+		for i := 0; i < size; i++ {
+			k, err := decodeValue(f, kt, opts, depth+1, byteBudget)
+			if err != nil {
+				return Value{}, err
+			}
+			v, err := decodeValue(f, vt, opts, depth+1, byteBudget)
+			if err != nil {
+				return Value{}, err
+			}
+			entries = append(entries, MapEntry{Key: k, Value: v})
+		}
+		if err := f.ReadMapEnd(); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: MAP, Entries: entries}, nil
+	case SET, LIST:
+		var et Type
+		var size int
+		var err error
+		if typeID == SET {
+			et, size, err = f.ReadSetBegin()
+		} else {
+			et, size, err = f.ReadListBegin()
+		}
+		if err != nil {
+			return Value{}, err
+		}
+		if err := checkDecodedContainerSize(size, opts); err != nil {
+			return Value{}, err
+		}
+		elems := make([]Value, 0, size)
+		for i := 0; i < size; i++ {
+			v, err := decodeValue(f, et, opts, depth+1, byteBudget)
+			if err != nil {
+				return Value{}, err
+			}
+			elems = append(elems, v)
+		}
+		if typeID == SET {
+			err = f.ReadSetEnd()
+		} else {
+			err = f.ReadListEnd()
+		}
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Type: typeID, Elems: elems}, nil
+	default:
+		return Value{}, NewProtocolException(INVALID_DATA, fmt.Sprintf("DecodeValue: unsupported type %v", typeID))
+	}
+}
+
+func checkDecodedContainerSize(size int, opts SkipOptions) error {
+	if size < 0 {
+		return NewProtocolException(NEGATIVE_SIZE, "DecodeValue: negative container size")
+	}
+	if size > opts.MaxContainerSize {
+		return NewProtocolException(SIZE_LIMIT, "DecodeValue: container size exceeds MaxContainerSize")
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) Meta Platforms, Inc. and affiliates.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// websocketClosed is the TransportExceptionType returned on a Read or
+// Write after the peer has sent a CLOSE frame (or the connection was
+// closed locally).
+const websocketClosed TransportExceptionType = 9
+
+const defaultPingInterval = 30 * time.Second
+
+// WebSocketOptions configures a websocketTransport.
+type WebSocketOptions struct {
+	// EnableCompression negotiates the permessage-deflate extension at
+	// handshake time. Compression is therefore opt-in per connection: a
+	// peer that doesn't request it talks uncompressed WebSocket frames.
+	EnableCompression bool
+	// PingInterval is how often a PING is sent to keep the connection
+	// alive. Zero means defaultPingInterval.
+	PingInterval time.Duration
+}
+
+// websocketTransport is a Transport that sends each Thrift message as a
+// single, unfragmented BINARY WebSocket frame. PING/PONG is answered
+// transparently; once a CLOSE frame is seen (or initiated), subsequent
+// Read/Write calls return a TransportException of type websocketClosed.
+type websocketTransport struct {
+	conn       *websocket.Conn
+	writeBuf   *bytes.Buffer
+	readBuf    []byte
+	readOff    int
+	closed     atomic.Bool
+	stopPinger chan struct{}
+}
+
+// newWebSocketTransport wraps an already-established *websocket.Conn
+// (either from Upgrade on the server side, or websocket.Dial on the
+// client side).
+func newWebSocketTransport(conn *websocket.Conn, opts WebSocketOptions) *websocketTransport {
+	t := &websocketTransport{
+		conn:       conn,
+		writeBuf:   &bytes.Buffer{},
+		stopPinger: make(chan struct{}),
+	}
+	conn.SetPingHandler(func(appData string) error {
+		return conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(5*time.Second))
+	})
+	conn.SetCloseHandler(func(code int, text string) error {
+		t.closed.Store(true)
+		return nil
+	})
+
+	interval := opts.PingInterval
+	if interval <= 0 {
+		interval = defaultPingInterval
+	}
+	go t.pingLoop(interval)
+
+	return t
+}
+
+func (t *websocketTransport) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+				return
+			}
+		case <-t.stopPinger:
+			return
+		}
+	}
+}
+
+// Write buffers b to be sent as part of the next Flush.
+func (t *websocketTransport) Write(b []byte) (int, error) {
+	if t.closed.Load() {
+		return 0, NewTransportException(websocketClosed, "websocketTransport: write after close")
+	}
+	return t.writeBuf.Write(b)
+}
+
+// Flush sends everything buffered since the last Flush as a single BINARY
+// WebSocket frame, so a Thrift message is never fragmented across frames.
+func (t *websocketTransport) Flush() error {
+	if t.closed.Load() {
+		return NewTransportException(websocketClosed, "websocketTransport: flush after close")
+	}
+	defer t.writeBuf.Reset()
+	if err := t.conn.WriteMessage(websocket.BinaryMessage, t.writeBuf.Bytes()); err != nil {
+		return NewTransportExceptionFromError(err)
+	}
+	return nil
+}
+
+// Read returns bytes from the most recently received BINARY message,
+// reading a new one (skipping/answering any control frames in between)
+// once the current one is exhausted.
+func (t *websocketTransport) Read(b []byte) (int, error) {
+	if t.readOff >= len(t.readBuf) {
+		if t.closed.Load() {
+			return 0, NewTransportException(websocketClosed, "websocketTransport: read after close")
+		}
+		msgType, data, err := t.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				t.closed.Store(true)
+				return 0, NewTransportException(websocketClosed, "websocketTransport: peer closed connection")
+			}
+			return 0, NewTransportExceptionFromError(err)
+		}
+		if msgType != websocket.BinaryMessage {
+			return 0, NewTransportException(UNKNOWN_TRANSPORT_EXCEPTION, "websocketTransport: expected a BINARY message")
+		}
+		t.readBuf = data
+		t.readOff = 0
+	}
+	n := copy(b, t.readBuf[t.readOff:])
+	t.readOff += n
+	return n, nil
+}
+
+// Close sends a CLOSE frame and tears down the connection.
+func (t *websocketTransport) Close() error {
+	if t.closed.Swap(true) {
+		return nil
+	}
+	close(t.stopPinger)
+	t.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(5*time.Second))
+	return t.conn.Close()
+}
+
+// UpgradeWebSocket upgrades an incoming HTTP request to a WebSocket
+// connection and wraps it as a Transport, for use from an http.Handler
+// fronting a Thrift server.
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request, opts WebSocketOptions) (*websocketTransport, error) {
+	upgrader := websocket.Upgrader{
+		EnableCompression: opts.EnableCompression,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, NewTransportExceptionFromError(err)
+	}
+	conn.EnableWriteCompression(opts.EnableCompression)
+	return newWebSocketTransport(conn, opts), nil
+}
+
+// DialWebSocket opens a client-side WebSocket connection to url and wraps
+// it as a Transport.
+func DialWebSocket(url string, opts WebSocketOptions) (*websocketTransport, error) {
+	dialer := websocket.Dialer{EnableCompression: opts.EnableCompression}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return nil, NewTransportExceptionFromError(err)
+	}
+	conn.EnableWriteCompression(opts.EnableCompression)
+	return newWebSocketTransport(conn, opts), nil
+}